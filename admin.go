@@ -0,0 +1,32 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/17xande/bd-chirpy/internal/auth"
+)
+
+// middlewareAdmin only lets requests through that carry the admin API key.
+// Without a configured key the admin API is disabled.
+func (cfg *apiConfig) middlewareAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.adminKey == "" {
+			respondWithError(w, http.StatusForbidden, "Admin API is disabled", nil)
+			return
+		}
+
+		key, err := auth.GetAPIKey(r.Header)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "Invalid or missing admin API key", err)
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(key), []byte(cfg.adminKey)) != 1 {
+			respondWithError(w, http.StatusUnauthorized, "Invalid admin API key", nil)
+			return
+		}
+
+		next(w, r)
+	}
+}
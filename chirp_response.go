@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/17xande/bd-chirpy/internal/auth"
+	"github.com/17xande/bd-chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// chirpFromDB copies the columns of a chirp row into its JSON form.
+// Counters that live in other tables are left at zero.
+func chirpFromDB(chirp database.Chirp) Chirp {
+	c := Chirp{
+		ID:        chirp.ID,
+		CreatedAt: chirp.CreatedAt,
+		UpdatedAt: chirp.UpdatedAt,
+		Body:      chirp.Body,
+		UserID:    chirp.UserID,
+		Media:     []Media{},
+		Pinned:    chirp.PinnedAt.Valid,
+	}
+
+	if chirp.ParentID.Valid {
+		parentID := chirp.ParentID.UUID
+		c.ParentID = &parentID
+	}
+
+	if !chirp.Published && chirp.PublishAt.Valid {
+		publishAt := chirp.PublishAt.Time
+		c.PublishAt = &publishAt
+	}
+
+	if chirp.IsRechirp {
+		c.RechirpOf = &RechirpRef{Deleted: !chirp.RechirpOfID.Valid}
+		if chirp.RechirpOfID.Valid {
+			originalID := chirp.RechirpOfID.UUID
+			c.RechirpOf.ID = &originalID
+		}
+	}
+
+	return c
+}
+
+const (
+	moderationVisible = "visible"
+	moderationFlagged = "flagged"
+	moderationHidden  = "hidden"
+)
+
+// chirpVisibleTo reports whether viewerID may see chirp. Hidden chirps and
+// scheduled chirps that aren't due yet are only visible to their author;
+// deleted chirps aren't visible to anyone.
+func chirpVisibleTo(chirp database.Chirp, viewerID uuid.UUID) bool {
+	if chirp.DeletedAt.Valid {
+		return false
+	}
+	if chirp.UserID == viewerID {
+		return true
+	}
+	return chirp.ModerationStatus != moderationHidden && chirp.Published
+}
+
+// getVisibleChirp loads a chirp for viewerID. Chirps the viewer may not see
+// are reported as sql.ErrNoRows, exactly like chirps that don't exist.
+func (cfg *apiConfig) getVisibleChirp(ctx context.Context, id, viewerID uuid.UUID) (database.Chirp, error) {
+	chirp, err := cfg.db.GetChirp(ctx, id)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	if !chirpVisibleTo(chirp, viewerID) {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+
+	return chirp, nil
+}
+
+// viewerID returns the user making the request, or uuid.Nil when the request
+// doesn't carry a valid access token. It's for endpoints that are public but
+// personalise their response for signed in users.
+func (cfg *apiConfig) viewerID(r *http.Request) uuid.UUID {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil
+	}
+
+	id, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		return uuid.Nil
+	}
+
+	return id
+}
+
+// chirpsResponse converts chirps into their JSON form, fills in the counters
+// that live in other tables and attaches the originals of rechirps. It uses a
+// fixed number of queries rather than a few per chirp. viewerID may be
+// uuid.Nil for anonymous requests.
+func (cfg *apiConfig) chirpsResponse(ctx context.Context, chirps []database.Chirp, viewerID uuid.UUID) ([]Chirp, error) {
+	res, err := cfg.chirpsWithCounts(ctx, chirps, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	originalIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		if chirp.IsRechirp && chirp.RechirpOfID.Valid {
+			originalIDs = append(originalIDs, chirp.RechirpOfID.UUID)
+		}
+	}
+	if len(originalIDs) == 0 {
+		return res, nil
+	}
+
+	rows, err := cfg.db.GetChirpsByIDs(ctx, originalIDs)
+	if err != nil {
+		return nil, fmt.Errorf("can't get rechirped chirps: %w", err)
+	}
+
+	originalRows := make([]database.Chirp, 0, len(rows))
+	for _, row := range rows {
+		if chirpVisibleTo(row, viewerID) {
+			originalRows = append(originalRows, row)
+		}
+	}
+
+	// Originals are only expanded one level deep; their own rechirp_of
+	// carries just the ID.
+	originals, err := cfg.chirpsWithCounts(ctx, originalRows, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uuid.UUID]*Chirp, len(originals))
+	for i := range originals {
+		byID[originals[i].ID] = &originals[i]
+	}
+
+	for i := range res {
+		ref := res[i].RechirpOf
+		if ref == nil || ref.ID == nil {
+			continue
+		}
+		if original, ok := byID[*ref.ID]; ok {
+			ref.Chirp = original
+		} else {
+			ref.Deleted = true
+		}
+	}
+
+	return res, nil
+}
+
+// chirpsWithCounts converts chirps into their JSON form and fills in the
+// counters and attachments that live in other tables, with one query per
+// table.
+func (cfg *apiConfig) chirpsWithCounts(ctx context.Context, chirps []database.Chirp, viewerID uuid.UUID) ([]Chirp, error) {
+	res := make([]Chirp, 0, len(chirps))
+	if len(chirps) == 0 {
+		return res, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		ids = append(ids, chirp.ID)
+	}
+
+	replyCounts, err := cfg.db.CountReplies(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("can't count replies: %w", err)
+	}
+
+	replies := make(map[uuid.UUID]int64, len(replyCounts))
+	for _, rc := range replyCounts {
+		replies[rc.ParentID.UUID] = rc.ReplyCount
+	}
+
+	likeCounts, err := cfg.db.CountLikes(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("can't count likes: %w", err)
+	}
+
+	likes := make(map[uuid.UUID]int64, len(likeCounts))
+	for _, lc := range likeCounts {
+		likes[lc.ChirpID] = lc.LikeCount
+	}
+
+	likedByViewer := map[uuid.UUID]bool{}
+	if viewerID != uuid.Nil {
+		liked, err := cfg.db.GetLikedChirpIDs(ctx, database.GetLikedChirpIDsParams{
+			UserID:   viewerID,
+			ChirpIds: ids,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("can't get liked chirps: %w", err)
+		}
+		for _, id := range liked {
+			likedByViewer[id] = true
+		}
+	}
+
+	mediaRows, err := cfg.db.GetMediaByChirpIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("can't get media: %w", err)
+	}
+
+	media := map[uuid.UUID][]Media{}
+	for _, m := range mediaRows {
+		media[m.ChirpID.UUID] = append(media[m.ChirpID.UUID], mediaFromDB(m))
+	}
+
+	previewRows, err := cfg.db.GetLinkPreviewsByChirpIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("can't get link previews: %w", err)
+	}
+
+	previews := make(map[uuid.UUID]database.LinkPreview, len(previewRows))
+	for _, p := range previewRows {
+		previews[p.ChirpID] = p
+	}
+
+	for _, chirp := range chirps {
+		c := chirpFromDB(chirp)
+		c.ReplyCount = replies[chirp.ID]
+		c.LikeCount = likes[chirp.ID]
+		c.LikedByMe = likedByViewer[chirp.ID]
+		c.Media = media[chirp.ID]
+		if c.Media == nil {
+			c.Media = []Media{}
+		}
+		if p, ok := previews[chirp.ID]; ok {
+			c.LinkPreview = linkPreviewFromDB(p)
+		}
+		res = append(res, c)
+	}
+
+	return res, nil
+}
+
+func (cfg *apiConfig) chirpResponse(ctx context.Context, chirp database.Chirp, viewerID uuid.UUID) (Chirp, error) {
+	res, err := cfg.chirpsResponse(ctx, []database.Chirp{chirp}, viewerID)
+	if err != nil {
+		return Chirp{}, err
+	}
+	return res[0], nil
+}
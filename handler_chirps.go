@@ -2,24 +2,44 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"sort"
 	"strings"
 	"time"
 
 	"github.com/17xande/bd-chirpy/internal/auth"
 	"github.com/17xande/bd-chirpy/internal/database"
+	"github.com/17xande/bd-chirpy/internal/moderation"
 	"github.com/google/uuid"
 )
 
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID          uuid.UUID    `json:"id"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	Body        string       `json:"body"`
+	UserID      uuid.UUID    `json:"user_id"`
+	ParentID    *uuid.UUID   `json:"parent_id"`
+	ReplyCount  int64        `json:"reply_count"`
+	LikeCount   int64        `json:"like_count"`
+	LikedByMe   bool         `json:"liked_by_me"`
+	RechirpOf   *RechirpRef  `json:"rechirp_of,omitempty"`
+	Media       []Media      `json:"media"`
+	LinkPreview *LinkPreview `json:"link_preview"`
+	PublishAt   *time.Time   `json:"publish_at,omitempty"`
+	Pinned      bool         `json:"pinned"`
+}
+
+// RechirpRef points at the chirp a rechirp or quote chirp shares. When the
+// original has been deleted, or the viewer may no longer see it, only Deleted
+// is set, so clients can render a tombstone in its place.
+type RechirpRef struct {
+	ID      *uuid.UUID `json:"id"`
+	Deleted bool       `json:"deleted"`
+	Chirp   *Chirp     `json:"chirp"`
 }
 
 func (cfg *apiConfig) handlerChirpsCreate(w http.ResponseWriter, r *http.Request) {
@@ -36,8 +56,11 @@ func (cfg *apiConfig) handlerChirpsCreate(w http.ResponseWriter, r *http.Request
 	}
 
 	type parameters struct {
-		Body   string    `json:"body"`
-		UserID uuid.UUID `json:"user_id"`
+		Body      string      `json:"body"`
+		UserID    uuid.UUID   `json:"user_id"`
+		ParentID  *uuid.UUID  `json:"parent_id"`
+		MediaIDs  []uuid.UUID `json:"media_ids"`
+		PublishAt *time.Time  `json:"publish_at"`
 	}
 
 	type response struct {
@@ -53,30 +76,77 @@ func (cfg *apiConfig) handlerChirpsCreate(w http.ResponseWriter, r *http.Request
 
 	params.UserID = ID
 
-	ok, cleanChirp, err := cfg.validateChirp(params.Body)
-	if !ok {
+	if len(params.MediaIDs) > maxChirpMedia {
+		respondWithError(w, http.StatusUnprocessableEntity, fmt.Sprintf("A chirp can have at most %d attachments", maxChirpMedia), nil)
+		return
+	}
+
+	moderated, err := cfg.validateChirp(params.Body)
+	if err != nil {
 		respondWithError(w, http.StatusUnprocessableEntity, "Can't valicate chirp", err)
 		return
 	}
 
 	chirpParams := database.CreateChirpsParams{
-		Body:   cleanChirp,
+		Body:   moderated.Text,
 		UserID: params.UserID,
 	}
 
-	chirp, err := cfg.db.CreateChirps(context.Background(), chirpParams)
+	if params.PublishAt != nil {
+		publishAt, err := validatePublishAt(*params.PublishAt, time.Now())
+		if err != nil {
+			respondWithError(w, http.StatusUnprocessableEntity, "Invalid publish_at", err)
+			return
+		}
+		chirpParams.PublishAt = sql.NullTime{Time: publishAt, Valid: true}
+	}
+
+	if params.ParentID != nil {
+		parent, err := cfg.getVisibleChirp(context.Background(), *params.ParentID, ID)
+		if err != nil {
+			respondWithError(w, http.StatusUnprocessableEntity, "Can't find the chirp being replied to", err)
+			return
+		}
+		chirpParams.ParentID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+	}
+
+	tx, err := cfg.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	chirp, err := qtx.CreateChirps(context.Background(), chirpParams)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error creating chirp", err)
 		return
 	}
 
-	res := response{Chirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
-	}}
+	if err := attachMedia(context.Background(), qtx, chirp.ID, ID, params.MediaIDs); err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Can't attach media", err)
+		return
+	}
+
+	if err := processChirp(context.Background(), qtx, chirp, moderated); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error processing chirp", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error committing chirp", err)
+		return
+	}
+	cfg.linkPreviews.notify()
+
+	resChirp, err := cfg.chirpResponse(context.Background(), chirp, ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't build chirp response", err)
+		return
+	}
+
+	res := response{resChirp}
 
 	respondWithJSON(w, http.StatusCreated, res)
 }
@@ -87,47 +157,89 @@ func (cfg *apiConfig) handlerChirpsGet(w http.ResponseWriter, r *http.Request) {
 	}
 
 	queryParams := r.URL.Query()
-	author_id := queryParams.Get("author_id")
 	sortParam := queryParams.Get("sort")
 	if sortParam == "" {
 		sortParam = "asc"
 	}
-	var chirps []database.Chirp
-	if author_id == "" {
-		var err error
-		chirps, err = cfg.db.GetChirps(context.Background())
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Can't get all chirps", err)
-			return
-		}
-	} else {
-		uid, err := uuid.Parse(author_id)
+	if sortParam != "asc" && sortParam != "desc" {
+		respondWithError(w, http.StatusBadRequest, "sort must be asc or desc", nil)
+		return
+	}
+
+	pageSize, cursor, err := parsePageParams(queryParams)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid pagination parameters", err)
+		return
+	}
+
+	viewerID := cfg.viewerID(r)
+
+	params := database.ListChirpsAscParams{
+		AfterCreatedAt: cursor.createdAt(),
+		AfterID:        cursor.id(),
+		ViewerID:       uuid.NullUUID{UUID: viewerID, Valid: viewerID != uuid.Nil},
+	}
+
+	if author_id := queryParams.Get("author_id"); author_id != "" {
+		uid, err := cfg.resolveUserRef(context.Background(), author_id)
 		if err != nil {
-			respondWithError(w, http.StatusUnprocessableEntity, "Invalid author_id", err)
+			respondWithUserRefError(w, err)
 			return
 		}
-		chirps, err = cfg.db.GetChirpsByAuthor(context.Background(), uid)
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Can't get chirps for this user", err)
-			return
+		params.AuthorID = uuid.NullUUID{UUID: uid, Valid: true}
+	}
+
+	if params.Since, err = parseTimeParam(queryParams.Get("since")); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid since", err)
+		return
+	}
+
+	if params.Until, err = parseTimeParam(queryParams.Get("until")); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid until", err)
+		return
+	}
+
+	// Pinned chirps lead the first page of an author's chirps and are left
+	// out of every page of the listing itself, unless the client asked for a
+	// time range they may not fall into.
+	pinned := []database.Chirp{}
+	limit := pageSize
+	if params.AuthorID.Valid && !params.Since.Valid && !params.Until.Valid {
+		params.ExcludePinned = true
+		if cursor == nil {
+			pinned, err = cfg.visiblePinnedChirps(context.Background(), params.AuthorID.UUID, viewerID)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Can't get chirps", err)
+				return
+			}
+			limit = pinnedPageSize(pageSize, pinned)
 		}
 	}
 
+	// Fetch one extra row to find out whether there is a next page.
+	params.PageSize = limit + 1
+
+	var chirps []database.Chirp
 	if sortParam == "desc" {
-		sort.Slice(chirps, func(i, j int) bool { return chirps[i].CreatedAt.After(chirps[j].CreatedAt) })
+		chirps, err = cfg.db.ListChirpsDesc(context.Background(), database.ListChirpsDescParams(params))
+	} else {
+		chirps, err = cfg.db.ListChirpsAsc(context.Background(), params)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't get chirps", err)
+		return
 	}
 
-	resChirps := []Chirp{}
+	if len(chirps) > int(limit) {
+		chirps = chirps[:limit]
+		setNextPageLink(w, r, pageEndCursor(chirps, sortParam == "desc"))
+	}
+	chirps = append(pinned, chirps...)
 
-	for _, chirp := range chirps {
-		c := Chirp{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
-		}
-		resChirps = append(resChirps, c)
+	resChirps, err := cfg.chirpsResponse(context.Background(), chirps, viewerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't build chirps response", err)
+		return
 	}
 
 	respondWithJSON(w, http.StatusOK, resChirps)
@@ -142,50 +254,47 @@ func (cfg *apiConfig) handlerChirpGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	chirp, err := cfg.db.GetChirp(context.Background(), uid)
+	chirp, err := cfg.getVisibleChirp(context.Background(), uid, cfg.viewerID(r))
 	if err != nil {
 		respondWithError(w, http.StatusNotFound, "Can't get chirp with this ID", err)
 		return
 	}
 
-	res := Chirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
+	res, err := cfg.chirpResponse(context.Background(), chirp, cfg.viewerID(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't build chirp response", err)
+		return
 	}
 
 	respondWithJSON(w, http.StatusOK, res)
 }
 
-func (cfg *apiConfig) validateChirp(chirp string) (bool, string, error) {
-	const maxChirpLength = 140
-	if len(chirp) > maxChirpLength {
-		return false, "", fmt.Errorf("chirp is too long")
-	}
+// validateChirp runs a chirp body through the moderation engine. The
+// returned result holds the body to store.
+func (cfg *apiConfig) validateChirp(chirp string) (moderation.Result, error) {
+	return cfg.moderation.Check(chirp)
+}
 
-	badWords := map[string]struct{}{
-		"kerfuffle": {},
-		"sharbert":  {},
-		"fornax":    {},
+// processChirp does the work that follows writing a chirp's body: it indexes
+// its tags, queues a link preview and flags the chirp when moderation asked
+// for it. Callers run it in the transaction that writes the chirp and wake the
+// link preview worker once that transaction commits.
+func processChirp(ctx context.Context, q *database.Queries, chirp database.Chirp, moderated moderation.Result) error {
+	if err := indexChirpTags(ctx, q, chirp); err != nil {
+		return fmt.Errorf("can't index chirp: %w", err)
 	}
 
-	cleaned := getCleanedBody(chirp, badWords)
-
-	return true, cleaned, nil
-}
+	if err := queueLinkPreview(ctx, q, chirp); err != nil {
+		return fmt.Errorf("can't queue link preview: %w", err)
+	}
 
-func getCleanedBody(body string, badWords map[string]struct{}) string {
-	words := strings.Split(body, " ")
-	for i, word := range words {
-		loweredWord := strings.ToLower(word)
-		if _, ok := badWords[loweredWord]; ok {
-			words[i] = "****"
+	if moderated.Flagged {
+		if err := flagChirp(ctx, q, chirp.ID, uuid.NullUUID{}, moderated.Reason()); err != nil {
+			return fmt.Errorf("can't flag chirp: %w", err)
 		}
 	}
 
-	return strings.Join(words, " ")
+	return nil
 }
 
 func (cfg *apiConfig) handlerChirpDelete(w http.ResponseWriter, r *http.Request) {
@@ -209,18 +318,19 @@ func (cfg *apiConfig) handlerChirpDelete(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	_, err = cfg.db.GetChirp(context.Background(), uid)
+	_, err = cfg.getVisibleChirp(context.Background(), uid, user_id)
 	if err != nil {
 		respondWithError(w, http.StatusNotFound, "Can't get chirp with this ID", err)
 		return
 	}
 
-	args := database.DeleteChirpParams{
+	args := database.SoftDeleteChirpParams{
 		ID:     uid,
 		UserID: user_id,
 	}
 
-	rows, err := cfg.db.DeleteChirp(context.Background(), args)
+	// The chirp can be restored until the purge job removes it for good.
+	rows, err := cfg.db.SoftDeleteChirp(context.Background(), args)
 	if err != nil || rows != 1 {
 		respondWithError(w, http.StatusForbidden, "Couldn't delete this chirp", err)
 		return
@@ -228,3 +338,180 @@ func (cfg *apiConfig) handlerChirpDelete(w http.ResponseWriter, r *http.Request)
 
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
+
+var (
+	errPlainRechirpEdit = errors.New("plain rechirps can't be edited")
+	errEmptyQuote       = errors.New("a quote must have a body")
+)
+
+// checkChirpEdit keeps rechirps what they are: giving a plain rechirp a body
+// would turn it into a quote and get around the one rechirp per chirp rule,
+// and a quote without a body would look like a plain rechirp.
+func checkChirpEdit(old database.Chirp, body string) error {
+	if !old.IsRechirp {
+		return nil
+	}
+	if old.Body == "" {
+		return errPlainRechirpEdit
+	}
+	if strings.TrimSpace(body) == "" {
+		return errEmptyQuote
+	}
+	return nil
+}
+
+func (cfg *apiConfig) handlerChirpUpdate(w http.ResponseWriter, r *http.Request) {
+	chirpId := r.PathValue("id")
+
+	uid, err := uuid.Parse(chirpId)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Can't parse ID: "+chirpId, err)
+		return
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	user_id, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Couldn't decode parameters", err)
+		return
+	}
+
+	moderated, err := cfg.validateChirp(params.Body)
+	if err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Can't validate chirp", err)
+		return
+	}
+
+	tx, err := cfg.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	old, err := qtx.GetChirpForUpdate(context.Background(), uid)
+	if err == nil && old.DeletedAt.Valid {
+		err = sql.ErrNoRows
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Can't get chirp with this ID", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirp", err)
+		return
+	}
+
+	if old.UserID != user_id {
+		respondWithError(w, http.StatusForbidden, "Couldn't edit this chirp", nil)
+		return
+	}
+
+	err = checkChirpEdit(old, params.Body)
+	if errors.Is(err, errPlainRechirpEdit) {
+		respondWithError(w, http.StatusForbidden, "Couldn't edit this chirp", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Can't validate chirp", err)
+		return
+	}
+
+	_, err = qtx.CreateChirpRevision(context.Background(), database.CreateChirpRevisionParams{
+		ChirpID:   old.ID,
+		Body:      old.Body,
+		CreatedAt: old.UpdatedAt,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving chirp revision", err)
+		return
+	}
+
+	chirp, err := qtx.UpdateChirp(context.Background(), database.UpdateChirpParams{
+		Body:   moderated.Text,
+		ID:     old.ID,
+		UserID: user_id,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating chirp", err)
+		return
+	}
+
+	if err := processChirp(context.Background(), qtx, chirp, moderated); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error processing chirp", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error committing chirp update", err)
+		return
+	}
+	cfg.linkPreviews.notify()
+
+	res, err := cfg.chirpResponse(context.Background(), chirp, user_id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't build chirp response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, res)
+}
+
+type ChirpRevision struct {
+	ID         uuid.UUID `json:"id"`
+	ChirpID    uuid.UUID `json:"chirp_id"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+func (cfg *apiConfig) handlerChirpHistory(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Can't parse ID: "+id, err)
+		return
+	}
+
+	if _, err := cfg.getVisibleChirp(context.Background(), uid, cfg.viewerID(r)); err != nil {
+		respondWithError(w, http.StatusNotFound, "Can't get chirp with this ID", err)
+		return
+	}
+
+	revisions, err := cfg.db.GetChirpRevisions(context.Background(), uid)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't get chirp history", err)
+		return
+	}
+
+	res := []ChirpRevision{}
+	for _, rev := range revisions {
+		res = append(res, ChirpRevision{
+			ID:         rev.ID,
+			ChirpID:    rev.ChirpID,
+			Body:       rev.Body,
+			CreatedAt:  rev.CreatedAt,
+			ReplacedAt: rev.ReplacedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, res)
+}
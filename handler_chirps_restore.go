@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/17xande/bd-chirpy/internal/auth"
+	"github.com/17xande/bd-chirpy/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	defaultRestoreWindow = 7 * 24 * time.Hour
+	purgeInterval        = time.Hour
+)
+
+// handlerChirpRestore brings back a chirp its author deleted, as long as the
+// restore window hasn't passed yet.
+func (cfg *apiConfig) handlerChirpRestore(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	id := r.PathValue("id")
+	chirpID, err := uuid.Parse(id)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Can't parse ID: "+id, err)
+		return
+	}
+
+	chirp, err := cfg.db.RestoreChirp(context.Background(), database.RestoreChirpParams{
+		ID:            chirpID,
+		UserID:        userID,
+		WindowSeconds: cfg.restoreWindow.Seconds(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Can't find a restorable chirp with this ID", err)
+		return
+	}
+	// A deleted rechirp can't come back once the chirp has been rechirped
+	// again.
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		respondWithError(w, http.StatusConflict, "Chirp already rechirped", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error restoring chirp", err)
+		return
+	}
+
+	res, err := cfg.chirpResponse(context.Background(), chirp, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't build chirp response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, res)
+}
+
+// runPurger permanently deletes chirps whose restore window has passed.
+func (cfg *apiConfig) runPurger(ctx context.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		rows, err := cfg.purgeDeletedChirps(ctx)
+		if err != nil {
+			log.Printf("Error purging deleted chirps: %v", err)
+		} else if rows > 0 {
+			log.Printf("Purged %d deleted chirps", rows)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeDeletedChirps deletes chirps whose restore window has passed together
+// with their media. Deleted_at is set by the database, so the window is
+// measured on its clock as well.
+func (cfg *apiConfig) purgeDeletedChirps(ctx context.Context) (int64, error) {
+	tx, err := cfg.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("can't begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	media, err := qtx.DeletePurgedChirpMedia(ctx, cfg.restoreWindow.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("can't delete media: %w", err)
+	}
+
+	rows, err := qtx.PurgeDeletedChirps(ctx, cfg.restoreWindow.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("can't delete chirps: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("can't commit purge: %w", err)
+	}
+
+	cfg.deleteMediaFiles(ctx, media)
+
+	return rows, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/17xande/bd-chirpy/internal/database"
+)
+
+func TestCheckChirpEdit(t *testing.T) {
+	tests := []struct {
+		name    string
+		old     database.Chirp
+		body    string
+		wantErr error
+	}{
+		{
+			name: "Chirp",
+			old:  database.Chirp{Body: "hello"},
+			body: "hello world",
+		},
+		{
+			name:    "Plain rechirp gets a body",
+			old:     database.Chirp{IsRechirp: true},
+			body:    "now a quote",
+			wantErr: errPlainRechirpEdit,
+		},
+		{
+			name: "Quote",
+			old:  database.Chirp{IsRechirp: true, Body: "look at this"},
+			body: "look at this!",
+		},
+		{
+			name:    "Quote loses its body",
+			old:     database.Chirp{IsRechirp: true, Body: "look at this"},
+			body:    "  ",
+			wantErr: errEmptyQuote,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkChirpEdit(tt.old, tt.body); !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkChirpEdit() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/17xande/bd-chirpy/internal/auth"
+	"github.com/17xande/bd-chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// maxDraftLength leaves room for drafts that still need trimming; the chirp
+// limit is only enforced when a draft is published.
+const maxDraftLength = 10 * maxChirpLength
+
+type Draft struct {
+	ID        uuid.UUID `json:"id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func draftFromDB(draft database.Draft) Draft {
+	return Draft{
+		ID:        draft.ID,
+		Body:      draft.Body,
+		CreatedAt: draft.CreatedAt,
+		UpdatedAt: draft.UpdatedAt,
+	}
+}
+
+func validateDraft(body string) error {
+	if utf8.RuneCountInString(body) > maxDraftLength {
+		return fmt.Errorf("draft is longer than %d characters", maxDraftLength)
+	}
+	return nil
+}
+
+func (cfg *apiConfig) handlerDraftsCreate(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Couldn't decode parameters", err)
+		return
+	}
+
+	if err := validateDraft(params.Body); err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Invalid draft", err)
+		return
+	}
+
+	draft, err := cfg.db.CreateDraft(context.Background(), database.CreateDraftParams{
+		UserID: userID,
+		Body:   params.Body,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating draft", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, draftFromDB(draft))
+}
+
+func (cfg *apiConfig) handlerDraftsGet(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	drafts, err := cfg.db.GetDrafts(context.Background(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't get drafts", err)
+		return
+	}
+
+	res := make([]Draft, 0, len(drafts))
+	for _, draft := range drafts {
+		res = append(res, draftFromDB(draft))
+	}
+
+	respondWithJSON(w, http.StatusOK, res)
+}
+
+func (cfg *apiConfig) handlerDraftGet(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	id := r.PathValue("id")
+	draftID, err := uuid.Parse(id)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Can't parse ID: "+id, err)
+		return
+	}
+
+	draft, err := cfg.db.GetDraft(context.Background(), database.GetDraftParams{
+		ID:     draftID,
+		UserID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Can't get draft with this ID", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting draft", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, draftFromDB(draft))
+}
+
+func (cfg *apiConfig) handlerDraftUpdate(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	id := r.PathValue("id")
+	draftID, err := uuid.Parse(id)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Can't parse ID: "+id, err)
+		return
+	}
+
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Couldn't decode parameters", err)
+		return
+	}
+
+	if err := validateDraft(params.Body); err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Invalid draft", err)
+		return
+	}
+
+	draft, err := cfg.db.UpdateDraft(context.Background(), database.UpdateDraftParams{
+		Body:   params.Body,
+		ID:     draftID,
+		UserID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Can't get draft with this ID", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating draft", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, draftFromDB(draft))
+}
+
+func (cfg *apiConfig) handlerDraftDelete(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	id := r.PathValue("id")
+	draftID, err := uuid.Parse(id)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Can't parse ID: "+id, err)
+		return
+	}
+
+	rows, err := cfg.db.DeleteDraft(context.Background(), database.DeleteDraftParams{
+		ID:     draftID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting draft", err)
+		return
+	}
+	if rows == 0 {
+		respondWithError(w, http.StatusNotFound, "Can't get draft with this ID", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerDraftPublish turns a draft into a chirp. The chirp is created and the
+// draft deleted in one transaction, so a draft is never published twice and
+// never lost.
+func (cfg *apiConfig) handlerDraftPublish(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	id := r.PathValue("id")
+	draftID, err := uuid.Parse(id)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Can't parse ID: "+id, err)
+		return
+	}
+
+	tx, err := cfg.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	draft, err := qtx.GetDraftForUpdate(context.Background(), database.GetDraftForUpdateParams{
+		ID:     draftID,
+		UserID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Can't get draft with this ID", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting draft", err)
+		return
+	}
+
+	moderated, err := cfg.validateChirp(draft.Body)
+	if err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Can't validate chirp", err)
+		return
+	}
+
+	chirp, err := qtx.CreateChirps(context.Background(), database.CreateChirpsParams{
+		Body:   moderated.Text,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating chirp", err)
+		return
+	}
+
+	if err := processChirp(context.Background(), qtx, chirp, moderated); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error processing chirp", err)
+		return
+	}
+
+	if _, err := qtx.DeleteDraft(context.Background(), database.DeleteDraftParams{
+		ID:     draft.ID,
+		UserID: userID,
+	}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting draft", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error committing chirp", err)
+		return
+	}
+	cfg.linkPreviews.notify()
+
+	res, err := cfg.chirpResponse(context.Background(), chirp, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't build chirp response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, res)
+}
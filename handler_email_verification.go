@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/17xande/bd-chirpy/internal/auth"
+	"github.com/17xande/bd-chirpy/internal/database"
+	"github.com/17xande/bd-chirpy/internal/mailer"
+)
+
+const emailVerificationTTL = 24 * time.Hour
+
+// sendVerificationEmail mails user a link that confirms their current email
+// address. Links sent earlier stop working, so only the newest address can
+// be verified.
+func (cfg *apiConfig) sendVerificationEmail(ctx context.Context, user database.User) error {
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return fmt.Errorf("can't make verification token: %w", err)
+	}
+
+	tx, err := cfg.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("can't begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	if err := qtx.DeleteEmailVerificationTokens(ctx, user.ID); err != nil {
+		return fmt.Errorf("can't delete old verification tokens: %w", err)
+	}
+
+	err = qtx.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		Email:     user.Email,
+		ExpiresAt: time.Now().UTC().Add(emailVerificationTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("can't store verification token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("can't commit verification token: %w", err)
+	}
+
+	link := cfg.baseURL + "/api/users/verify?token=" + url.QueryEscape(token)
+	return cfg.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your Chirpy email address",
+		Body: "Open this link to confirm your email address:\n\n" +
+			link + "\n\n" +
+			"The link expires in 24 hours. If you didn't sign up for Chirpy you can ignore this email.\n",
+	})
+}
+
+// trySendVerificationEmail sends the verification email in the background, so
+// a slow mail server doesn't hold up the request that changed the address.
+// Failures are only logged; users can ask for a new link later.
+func (cfg *apiConfig) trySendVerificationEmail(user database.User) {
+	go func() {
+		if err := cfg.sendVerificationEmail(context.Background(), user); err != nil {
+			log.Printf("Can't send verification email to user %s: %v", user.ID, err)
+		}
+	}()
+}
+
+func (cfg *apiConfig) handlerEmailVerify(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing verification token", nil)
+		return
+	}
+
+	tx, err := cfg.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	// Expiry is set and checked on Go's clock, like refresh tokens.
+	verification, err := qtx.UseEmailVerificationToken(context.Background(), database.UseEmailVerificationTokenParams{
+		TokenHash: auth.HashToken(token),
+		Now:       time.Now().UTC(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired verification token", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error checking verification token", err)
+		return
+	}
+
+	// The token is only good for the address it was sent to.
+	user, err := qtx.MarkUserEmailVerified(context.Background(), database.MarkUserEmailVerifiedParams{
+		ID:    verification.UserID,
+		Email: verification.Email,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired verification token", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error verifying email", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error committing verification", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, userFromDB(user))
+}
+
+// handlerEmailVerifyResend sends a new verification link to the signed in
+// user, e.g. after the first one expired.
+func (cfg *apiConfig) handlerEmailVerifyResend(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	user, err := cfg.db.GetUser(context.Background(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting user", err)
+		return
+	}
+
+	if user.EmailVerifiedAt.Valid {
+		respondWithError(w, http.StatusConflict, "Email is already verified", nil)
+		return
+	}
+
+	if err := cfg.sendVerificationEmail(context.Background(), user); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't send verification email", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
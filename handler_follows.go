@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/17xande/bd-chirpy/internal/auth"
+	"github.com/17xande/bd-chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type Follow struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+func (cfg *apiConfig) handlerFollowCreate(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	followerID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	followeeID, err := cfg.resolveUserRef(context.Background(), r.PathValue("id"))
+	if err != nil {
+		respondWithUserRefError(w, err)
+		return
+	}
+
+	if followeeID == followerID {
+		respondWithError(w, http.StatusUnprocessableEntity, "Users can't follow themselves", nil)
+		return
+	}
+
+	if _, err := cfg.db.GetUser(context.Background(), followeeID); err != nil {
+		respondWithError(w, http.StatusNotFound, "Can't get user with this ID", err)
+		return
+	}
+
+	err = cfg.db.CreateFollow(context.Background(), database.CreateFollowParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error following user", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerFollowDelete(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	followerID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	followeeID, err := cfg.resolveUserRef(context.Background(), r.PathValue("id"))
+	if err != nil {
+		respondWithUserRefError(w, err)
+		return
+	}
+
+	_, err = cfg.db.DeleteFollow(context.Background(), database.DeleteFollowParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error unfollowing user", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerFollowersGet(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.resolveUserRef(context.Background(), r.PathValue("id"))
+	if err != nil {
+		respondWithUserRefError(w, err)
+		return
+	}
+
+	follows, err := cfg.db.GetFollowers(context.Background(), uid)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't get followers", err)
+		return
+	}
+
+	res := []Follow{}
+	for _, f := range follows {
+		res = append(res, Follow{UserID: f.FollowerID, FollowedAt: f.CreatedAt})
+	}
+
+	respondWithJSON(w, http.StatusOK, res)
+}
+
+func (cfg *apiConfig) handlerFollowingGet(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.resolveUserRef(context.Background(), r.PathValue("id"))
+	if err != nil {
+		respondWithUserRefError(w, err)
+		return
+	}
+
+	follows, err := cfg.db.GetFollowing(context.Background(), uid)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't get followed users", err)
+		return
+	}
+
+	res := []Follow{}
+	for _, f := range follows {
+		res = append(res, Follow{UserID: f.FolloweeID, FollowedAt: f.CreatedAt})
+	}
+
+	respondWithJSON(w, http.StatusOK, res)
+}
+
+// handlerTimeline returns chirps by the users the caller follows, newest
+// first, one page at a time.
+func (cfg *apiConfig) handlerTimeline(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	pageSize, cursor, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid pagination parameters", err)
+		return
+	}
+
+	chirps, err := cfg.db.GetTimeline(context.Background(), database.GetTimelineParams{
+		UserID:         userID,
+		AfterCreatedAt: cursor.createdAt(),
+		AfterID:        cursor.id(),
+		PageSize:       pageSize + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't get timeline", err)
+		return
+	}
+
+	if len(chirps) > int(pageSize) {
+		chirps = chirps[:pageSize]
+		last := chirps[len(chirps)-1]
+		setNextPageLink(w, r, pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	res, err := cfg.chirpsResponse(context.Background(), chirps, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't build chirps response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, res)
+}
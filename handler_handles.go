@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/17xande/bd-chirpy/internal/auth"
+	"github.com/17xande/bd-chirpy/internal/database"
+	"github.com/17xande/bd-chirpy/internal/handles"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const handleIndex = "users_handle_idx"
+
+var errInvalidUserRef = errors.New("expected a user ID or an @handle")
+
+// isHandleTaken reports whether err is the unique index on handles rejecting
+// a write.
+func isHandleTaken(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == handleIndex
+}
+
+func userHandle(user database.User) *string {
+	if !user.Handle.Valid {
+		return nil
+	}
+	handle := user.Handle.String
+	return &handle
+}
+
+// resolveUserRef turns a user reference from a URL into a user ID. References
+// are either a UUID or an @handle. Unknown handles are reported as
+// sql.ErrNoRows.
+func (cfg *apiConfig) resolveUserRef(ctx context.Context, ref string) (uuid.UUID, error) {
+	if handle, ok := strings.CutPrefix(ref, "@"); ok {
+		user, err := cfg.db.GetUserByHandle(ctx, handle)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		return user.ID, nil
+	}
+
+	id, err := uuid.Parse(ref)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: %q", errInvalidUserRef, ref)
+	}
+	return id, nil
+}
+
+// respondWithUserRefError reports why resolveUserRef failed: a malformed
+// reference, a user that doesn't exist, or a database error.
+func respondWithUserRefError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errInvalidUserRef):
+		respondWithError(w, http.StatusBadRequest, "Invalid user", err)
+	case errors.Is(err, sql.ErrNoRows):
+		respondWithError(w, http.StatusNotFound, "Can't find this user", err)
+	default:
+		respondWithError(w, http.StatusInternalServerError, "Error getting user", err)
+	}
+}
+
+func (cfg *apiConfig) handlerUserHandleSet(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	type parameters struct {
+		Handle string `json:"handle"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Couldn't decode parameters", err)
+		return
+	}
+
+	if err := handles.Validate(params.Handle); err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Invalid handle", err)
+		return
+	}
+
+	user, err := cfg.db.SetUserHandle(context.Background(), database.SetUserHandleParams{
+		Handle: sql.NullString{String: params.Handle, Valid: true},
+		ID:     userID,
+	})
+	if isHandleTaken(err) {
+		respondWithError(w, http.StatusConflict, "Handle is already taken", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error setting handle", err)
+		return
+	}
+
+	res, err := cfg.profileResponse(context.Background(), user)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't build profile response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, res)
+}
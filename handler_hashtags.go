@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/17xande/bd-chirpy/internal/database"
+)
+
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 7 * 24 * time.Hour
+	defaultTrendingLimit  = 10
+)
+
+type TrendingHashtag struct {
+	Tag        string `json:"tag"`
+	ChirpCount int64  `json:"chirp_count"`
+}
+
+func (cfg *apiConfig) handlerHashtagChirps(w http.ResponseWriter, r *http.Request) {
+	tag := strings.ToLower(strings.TrimPrefix(r.PathValue("tag"), "#"))
+
+	pageSize, cursor, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid pagination parameters", err)
+		return
+	}
+
+	chirps, err := cfg.db.GetChirpsByHashtag(context.Background(), database.GetChirpsByHashtagParams{
+		Tag:            tag,
+		AfterCreatedAt: cursor.createdAt(),
+		AfterID:        cursor.id(),
+		PageSize:       pageSize + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't get chirps for this hashtag", err)
+		return
+	}
+
+	if len(chirps) > int(pageSize) {
+		chirps = chirps[:pageSize]
+		last := chirps[len(chirps)-1]
+		setNextPageLink(w, r, pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	res, err := cfg.chirpsResponse(context.Background(), chirps, cfg.viewerID(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't build chirps response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, res)
+}
+
+// handlerHashtagsTrending ranks hashtags by how many chirps used them within
+// a sliding window ending now. The window is a Go duration such as "6h"; it is
+// measured on the database's clock, which set the chirps' creation times.
+func (cfg *apiConfig) handlerHashtagsTrending(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+
+	window := defaultTrendingWindow
+	if wp := queryParams.Get("window"); wp != "" {
+		d, err := time.ParseDuration(wp)
+		if err != nil || d <= 0 || d > maxTrendingWindow {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("window must be a duration up to %s", maxTrendingWindow), err)
+			return
+		}
+		window = d
+	}
+
+	limit := defaultTrendingLimit
+	if l := queryParams.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 {
+			respondWithError(w, http.StatusBadRequest, "Invalid limit", err)
+			return
+		}
+		limit = min(n, maxPageSize)
+	}
+
+	rows, err := cfg.db.GetTrendingHashtags(context.Background(), database.GetTrendingHashtagsParams{
+		WindowSeconds: window.Seconds(),
+		PageSize:      int32(limit),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't get trending hashtags", err)
+		return
+	}
+
+	res := []TrendingHashtag{}
+	for _, row := range rows {
+		res = append(res, TrendingHashtag{Tag: row.Tag, ChirpCount: row.ChirpCount})
+	}
+
+	respondWithJSON(w, http.StatusOK, res)
+}
+
+// handlerUserMentions lists the chirps that mention a user, newest first.
+// Mentions belong to the user who held the handle when the chirp was written.
+func (cfg *apiConfig) handlerUserMentions(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.resolveUserRef(context.Background(), r.PathValue("id"))
+	if err != nil {
+		respondWithUserRefError(w, err)
+		return
+	}
+
+	if _, err := cfg.db.GetUser(context.Background(), userID); err != nil {
+		respondWithUserRefError(w, err)
+		return
+	}
+
+	pageSize, cursor, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid pagination parameters", err)
+		return
+	}
+
+	// Fetch one extra row to find out whether there is a next page.
+	chirps, err := cfg.db.GetChirpsMentioning(context.Background(), database.GetChirpsMentioningParams{
+		UserID:         userID,
+		AfterCreatedAt: cursor.createdAt(),
+		AfterID:        cursor.id(),
+		PageSize:       pageSize + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't get mentions", err)
+		return
+	}
+
+	if len(chirps) > int(pageSize) {
+		chirps = chirps[:pageSize]
+		last := chirps[len(chirps)-1]
+		setNextPageLink(w, r, pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	resChirps, err := cfg.chirpsResponse(context.Background(), chirps, cfg.viewerID(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't build chirps response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, resChirps)
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/17xande/bd-chirpy/internal/auth"
+	"github.com/17xande/bd-chirpy/internal/database"
+	"github.com/17xande/bd-chirpy/internal/imaging"
+	"github.com/17xande/bd-chirpy/internal/storage"
+	"github.com/google/uuid"
+)
+
+const (
+	maxMediaSize     = 5 << 20
+	maxChirpMedia    = 4
+	mediaFormField   = "file"
+	mediaStoragePath = "media/"
+)
+
+// mediaImageOptions limit uploaded images. Originals are scaled down to
+// MaxDimension so clients never download camera-sized files.
+var mediaImageOptions = imaging.Options{
+	MaxPixels:     50_000_000,
+	MaxFrames:     300,
+	MaxDimension:  2048,
+	ThumbnailSize: 400,
+}
+
+// mediaExtensions lists the content types that may be uploaded and the file
+// extension they are stored with.
+var mediaExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+}
+
+type Media struct {
+	ID           uuid.UUID `json:"id"`
+	URL          string    `json:"url"`
+	ThumbnailURL *string   `json:"thumbnail_url"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func mediaFromDB(m database.Medium) Media {
+	media := Media{
+		ID:          m.ID,
+		URL:         "/api/media/" + m.ID.String(),
+		ContentType: m.ContentType,
+		Size:        m.SizeBytes,
+		Width:       m.Width,
+		Height:      m.Height,
+		CreatedAt:   m.CreatedAt,
+	}
+
+	if m.ThumbnailKey.Valid {
+		thumbnailURL := media.URL + "/thumbnail"
+		media.ThumbnailURL = &thumbnailURL
+	}
+
+	return media
+}
+
+func (cfg *apiConfig) handlerMediaUpload(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	// Leave some room for the multipart headers around the file.
+	r.Body = http.MaxBytesReader(w, r.Body, maxMediaSize+1<<10)
+	file, _, err := r.FormFile(mediaFormField)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, "File is too large", err)
+			return
+		}
+		respondWithError(w, http.StatusBadRequest, "Couldn't read file", err)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxMediaSize+1))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't read file", err)
+		return
+	}
+	if len(data) > maxMediaSize {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "File is too large", nil)
+		return
+	}
+
+	// The content type the client claims is ignored; only the file's
+	// contents decide what it is.
+	contentType := http.DetectContentType(data)
+	if _, ok := mediaExtensions[contentType]; !ok {
+		respondWithError(w, http.StatusUnsupportedMediaType, "Unsupported file type: "+contentType, nil)
+		return
+	}
+
+	// Processing re-encodes the image, which also strips EXIF metadata such
+	// as the location a photo was taken at.
+	processed, err := imaging.Process(data, mediaImageOptions)
+	if errors.Is(err, imaging.ErrUnsupportedFormat) {
+		respondWithError(w, http.StatusUnsupportedMediaType, "Couldn't decode image", err)
+		return
+	}
+	if errors.Is(err, imaging.ErrTooLarge) {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "Image is too large", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Couldn't process image", err)
+		return
+	}
+
+	id := uuid.New()
+	original := processed.Original
+	key := mediaStoragePath + id.String() + mediaExtensions[original.ContentType]
+	thumbnailKey := mediaStoragePath + id.String() + "_thumb" + mediaExtensions[processed.Thumbnail.ContentType]
+
+	if err := cfg.media.Put(context.Background(), key, bytes.NewReader(original.Data)); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error storing file", err)
+		return
+	}
+
+	if err := cfg.media.Put(context.Background(), thumbnailKey, bytes.NewReader(processed.Thumbnail.Data)); err != nil {
+		cfg.media.Delete(context.Background(), key)
+		respondWithError(w, http.StatusInternalServerError, "Error storing thumbnail", err)
+		return
+	}
+
+	media, err := cfg.db.CreateMedia(context.Background(), database.CreateMediaParams{
+		ID:           id,
+		UserID:       userID,
+		ContentType:  original.ContentType,
+		SizeBytes:    int64(len(original.Data)),
+		StorageKey:   key,
+		Width:        int32(original.Width),
+		Height:       int32(original.Height),
+		ThumbnailKey: sql.NullString{String: thumbnailKey, Valid: true},
+	})
+	if err != nil {
+		cfg.media.Delete(context.Background(), key)
+		cfg.media.Delete(context.Background(), thumbnailKey)
+		respondWithError(w, http.StatusInternalServerError, "Error saving media", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, mediaFromDB(media))
+}
+
+func (cfg *apiConfig) handlerMediaGet(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Can't parse ID: "+id, err)
+		return
+	}
+
+	media, err := cfg.getVisibleMedia(context.Background(), uid, cfg.viewerID(r))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Can't get media with this ID", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting media", err)
+		return
+	}
+
+	cfg.serveMediaObject(w, r, media.StorageKey, media.ContentType, media.CreatedAt)
+}
+
+func (cfg *apiConfig) handlerMediaThumbnailGet(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Can't parse ID: "+id, err)
+		return
+	}
+
+	media, err := cfg.getVisibleMedia(context.Background(), uid, cfg.viewerID(r))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Can't get media with this ID", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting media", err)
+		return
+	}
+
+	if !media.ThumbnailKey.Valid {
+		respondWithError(w, http.StatusNotFound, "This media has no thumbnail", nil)
+		return
+	}
+
+	contentType := "image/png"
+	if strings.HasSuffix(media.ThumbnailKey.String, mediaExtensions["image/jpeg"]) {
+		contentType = "image/jpeg"
+	}
+
+	cfg.serveMediaObject(w, r, media.ThumbnailKey.String, contentType, media.CreatedAt)
+}
+
+// getVisibleMedia loads an upload for viewerID. Media attached to a chirp are
+// only visible to those who may see the chirp; others get sql.ErrNoRows.
+func (cfg *apiConfig) getVisibleMedia(ctx context.Context, id, viewerID uuid.UUID) (database.Medium, error) {
+	media, err := cfg.db.GetMedia(ctx, id)
+	if err != nil {
+		return database.Medium{}, err
+	}
+
+	if media.ChirpID.Valid {
+		if _, err := cfg.getVisibleChirp(ctx, media.ChirpID.UUID, viewerID); err != nil {
+			return database.Medium{}, err
+		}
+	}
+
+	return media, nil
+}
+
+// serveMediaObject streams a stored file. Stored media never change, but the
+// chirp they belong to may be deleted or hidden, so clients have to revalidate
+// before using a cached copy.
+func (cfg *apiConfig) serveMediaObject(w http.ResponseWriter, r *http.Request, key, contentType string, modTime time.Time) {
+	f, err := cfg.media.Open(context.Background(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		respondWithError(w, http.StatusNotFound, "Can't get media with this ID", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error opening media", err)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, no-cache")
+	http.ServeContent(w, r, "", modTime, f)
+}
+
+// deleteMediaFiles removes the stored files of media whose rows are already
+// gone. A file that can't be removed is only logged, since nothing refers to
+// it anymore.
+func (cfg *apiConfig) deleteMediaFiles(ctx context.Context, media []database.Medium) {
+	for _, m := range media {
+		keys := []string{m.StorageKey}
+		if m.ThumbnailKey.Valid {
+			keys = append(keys, m.ThumbnailKey.String)
+		}
+		for _, key := range keys {
+			err := cfg.media.Delete(ctx, key)
+			if err != nil && !errors.Is(err, storage.ErrNotFound) {
+				log.Printf("Error deleting media file %s: %v", key, err)
+			}
+		}
+	}
+}
+
+// attachMedia links the uploads in ids to chirpID. Every upload must belong to
+// userID and not be attached to another chirp yet.
+func attachMedia(ctx context.Context, q *database.Queries, chirpID, userID uuid.UUID, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if len(ids) > maxChirpMedia {
+		return fmt.Errorf("a chirp can have at most %d attachments", maxChirpMedia)
+	}
+
+	unique := map[uuid.UUID]bool{}
+	for _, id := range ids {
+		unique[id] = true
+	}
+
+	rows, err := q.AttachMedia(ctx, database.AttachMediaParams{
+		ChirpID: uuid.NullUUID{UUID: chirpID, Valid: true},
+		Ids:     ids,
+		UserID:  userID,
+	})
+	if err != nil {
+		return err
+	}
+	if rows != int64(len(unique)) {
+		return errors.New("some media don't exist or are already attached")
+	}
+
+	return nil
+}
@@ -0,0 +1,511 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/17xande/bd-chirpy/internal/auth"
+	"github.com/17xande/bd-chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const totpIssuer = "Chirpy"
+
+var errMFALocked = errors.New("too many wrong two-factor codes")
+
+// confirmedTOTP returns the user's TOTP authenticator and whether it's
+// enabled. Enrolments that were never confirmed don't count, so an abandoned
+// setup can't lock anyone out.
+func (cfg *apiConfig) confirmedTOTP(ctx context.Context, userID uuid.UUID) (database.UserTotp, bool, error) {
+	totp, err := cfg.db.GetUserTOTP(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.UserTotp{}, false, nil
+	}
+	if err != nil {
+		return database.UserTotp{}, false, err
+	}
+	return totp, totp.ConfirmedAt.Valid, nil
+}
+
+func mfaLocked(totp database.UserTotp) bool {
+	return auth.Locked(totp.LockedUntil.Time, time.Now().UTC())
+}
+
+// checkSecondFactor accepts either a current TOTP code or an unused recovery
+// code. Wrong codes are counted, and too many in a row lock the second
+// factor for a while; errMFALocked is returned while it's locked.
+func (cfg *apiConfig) checkSecondFactor(ctx context.Context, totp database.UserTotp, code string) (bool, error) {
+	if mfaLocked(totp) {
+		return false, errMFALocked
+	}
+
+	ok, err := cfg.useSecondFactor(ctx, totp, code)
+	if err != nil {
+		return false, err
+	}
+	if ok {
+		return true, cfg.db.ResetTOTPFailures(ctx, totp.UserID)
+	}
+
+	failures, err := cfg.db.RecordTOTPFailure(ctx, totp.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	lockedUntil, lock := auth.DefaultMFALockout.LockUntil(failures, time.Now().UTC())
+	if !lock {
+		return false, nil
+	}
+
+	err = cfg.db.LockUserTOTP(ctx, database.LockUserTOTPParams{
+		UserID:      totp.UserID,
+		LockedUntil: sql.NullTime{Time: lockedUntil, Valid: true},
+	})
+	if err != nil {
+		return false, err
+	}
+	return false, errMFALocked
+}
+
+// useSecondFactor uses up a correct code: a TOTP code can't be replayed
+// within its validity window and a recovery code works only once.
+func (cfg *apiConfig) useSecondFactor(ctx context.Context, totp database.UserTotp, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+
+	if step, ok := auth.ValidateTOTP(totp.Secret, code, time.Now()); ok {
+		n, err := cfg.db.UseTOTPStep(ctx, database.UseTOTPStepParams{
+			UserID:       totp.UserID,
+			LastUsedStep: step,
+		})
+		return n == 1, err
+	}
+
+	n, err := cfg.db.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+		CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(code)),
+		UserID:   totp.UserID,
+	})
+	return n == 1, err
+}
+
+// replaceRecoveryCodes invalidates the user's recovery codes and returns a
+// new set. Only the hashes are stored, so this is the only time the codes
+// can be shown.
+func replaceRecoveryCodes(ctx context.Context, q *database.Queries, userID uuid.UUID) ([]string, error) {
+	if err := q.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return nil, fmt.Errorf("can't delete recovery codes: %w", err)
+	}
+
+	codes, err := auth.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("can't generate recovery codes: %w", err)
+	}
+
+	for _, code := range codes {
+		err := q.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+			CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(code)),
+			UserID:   userID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("can't store recovery code: %w", err)
+		}
+	}
+
+	return codes, nil
+}
+
+func (cfg *apiConfig) handlerMFAGet(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		TOTPEnabled            bool  `json:"totp_enabled"`
+		RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	_, enabled, err := cfg.confirmedTOTP(context.Background(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error checking two-factor authentication", err)
+		return
+	}
+
+	remaining, err := cfg.db.CountRecoveryCodes(context.Background(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error counting recovery codes", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		TOTPEnabled:            enabled,
+		RecoveryCodesRemaining: remaining,
+	})
+}
+
+// handlerTOTPEnroll creates a new TOTP secret. It isn't used for logins until
+// the user proves their authenticator has it with handlerTOTPConfirm;
+// enrolling again before that replaces the secret.
+func (cfg *apiConfig) handlerTOTPEnroll(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Secret     string `json:"secret"`
+		OTPAuthURI string `json:"otpauth_uri"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	user, err := cfg.db.GetUser(context.Background(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting user", err)
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error generating secret", err)
+		return
+	}
+
+	_, err = cfg.db.StartUserTOTP(context.Background(), database.StartUserTOTPParams{
+		UserID: userID,
+		Secret: secret,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled", nil)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error storing secret", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, response{
+		Secret:     secret,
+		OTPAuthURI: auth.TOTPURI(totpIssuer, user.Email, secret),
+	})
+}
+
+// handlerTOTPConfirm turns two-factor authentication on once the user sends a
+// code from their authenticator, and returns the first set of recovery
+// codes.
+func (cfg *apiConfig) handlerTOTPConfirm(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code string `json:"code"`
+	}
+
+	type response struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Couldn't decode parameters", err)
+		return
+	}
+
+	totp, err := cfg.db.GetUserTOTP(context.Background(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Two-factor authentication hasn't been set up", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting two-factor authentication", err)
+		return
+	}
+
+	if totp.ConfirmedAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled", nil)
+		return
+	}
+
+	step, ok := auth.ValidateTOTP(totp.Secret, strings.TrimSpace(params.Code), time.Now())
+	if !ok {
+		respondWithError(w, http.StatusUnprocessableEntity, "Invalid code", nil)
+		return
+	}
+
+	tx, err := cfg.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	_, err = qtx.ConfirmUserTOTP(context.Background(), database.ConfirmUserTOTPParams{
+		UserID:       userID,
+		LastUsedStep: step,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled", nil)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error enabling two-factor authentication", err)
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(context.Background(), qtx, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating recovery codes", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error committing two-factor authentication", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{RecoveryCodes: codes})
+}
+
+// handlerTOTPDisable turns two-factor authentication off. It asks for both
+// factors, so neither a stolen access token nor a stolen password is enough.
+func (cfg *apiConfig) handlerTOTPDisable(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Couldn't decode parameters", err)
+		return
+	}
+
+	user, err := cfg.db.GetUser(context.Background(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting user", err)
+		return
+	}
+
+	if err := auth.CheckPasswordHash(params.Password, user.HashedPassword); err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Incorrect password", err)
+		return
+	}
+
+	totp, enabled, err := cfg.confirmedTOTP(context.Background(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting two-factor authentication", err)
+		return
+	}
+	if !enabled {
+		respondWithError(w, http.StatusNotFound, "Two-factor authentication isn't enabled", nil)
+		return
+	}
+
+	ok, err := cfg.checkSecondFactor(context.Background(), totp, params.Code)
+	if errors.Is(err, errMFALocked) {
+		respondWithError(w, http.StatusTooManyRequests, "Too many wrong codes, try again later", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error checking code", err)
+		return
+	}
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Invalid code", nil)
+		return
+	}
+
+	tx, err := cfg.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	if err := qtx.DeleteUserTOTP(context.Background(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error disabling two-factor authentication", err)
+		return
+	}
+
+	if err := qtx.DeleteRecoveryCodes(context.Background(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting recovery codes", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error committing two-factor authentication", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerRecoveryCodesCreate replaces the user's recovery codes, e.g. after
+// most of them were used.
+func (cfg *apiConfig) handlerRecoveryCodesCreate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code string `json:"code"`
+	}
+
+	type response struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Couldn't decode parameters", err)
+		return
+	}
+
+	totp, enabled, err := cfg.confirmedTOTP(context.Background(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting two-factor authentication", err)
+		return
+	}
+	if !enabled {
+		respondWithError(w, http.StatusNotFound, "Two-factor authentication isn't enabled", nil)
+		return
+	}
+
+	ok, err := cfg.checkSecondFactor(context.Background(), totp, params.Code)
+	if errors.Is(err, errMFALocked) {
+		respondWithError(w, http.StatusTooManyRequests, "Too many wrong codes, try again later", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error checking code", err)
+		return
+	}
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Invalid code", nil)
+		return
+	}
+
+	tx, err := cfg.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	codes, err := replaceRecoveryCodes(context.Background(), cfg.db.WithTx(tx), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating recovery codes", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error committing recovery codes", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, response{RecoveryCodes: codes})
+}
+
+// handlerUserLoginMFA completes a login started by handlerUserLogin.
+func (cfg *apiConfig) handlerUserLoginMFA(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Couldn't decode parameters", err)
+		return
+	}
+
+	userID, err := auth.ValidateMFAChallenge(params.MFAToken, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired MFA token", err)
+		return
+	}
+
+	totp, enabled, err := cfg.confirmedTOTP(context.Background(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting two-factor authentication", err)
+		return
+	}
+	if !enabled {
+		respondWithError(w, http.StatusUnauthorized, "Two-factor authentication isn't enabled", nil)
+		return
+	}
+
+	ok, err := cfg.checkSecondFactor(context.Background(), totp, params.Code)
+	if errors.Is(err, errMFALocked) {
+		respondWithError(w, http.StatusTooManyRequests, "Too many wrong codes, try again later", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error checking code", err)
+		return
+	}
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Invalid code", nil)
+		return
+	}
+
+	user, err := cfg.db.GetUser(context.Background(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting user", err)
+		return
+	}
+
+	cfg.respondWithSession(w, user)
+}
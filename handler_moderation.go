@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/17xande/bd-chirpy/internal/database"
+	"github.com/17xande/bd-chirpy/internal/moderation"
+)
+
+const maxChirpLength = 140
+
+// dbWordSource feeds the moderation_words table to the moderation engine.
+type dbWordSource struct {
+	db *database.Queries
+}
+
+func (s dbWordSource) Rules(ctx context.Context) ([]moderation.Rule, error) {
+	words, err := s.db.GetModerationWords(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]moderation.Rule, 0, len(words))
+	for _, w := range words {
+		rules = append(rules, moderation.Rule{Word: w.Word, Action: moderation.Action(w.Action)})
+	}
+
+	return rules, nil
+}
+
+type ModerationWord struct {
+	Word      string            `json:"word"`
+	Action    moderation.Action `json:"action"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+func (cfg *apiConfig) handlerModerationWordsGet(w http.ResponseWriter, r *http.Request) {
+	words, err := cfg.db.GetModerationWords(context.Background())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't get moderation words", err)
+		return
+	}
+
+	res := []ModerationWord{}
+	for _, word := range words {
+		res = append(res, ModerationWord{
+			Word:      word.Word,
+			Action:    moderation.Action(word.Action),
+			CreatedAt: word.CreatedAt,
+			UpdatedAt: word.UpdatedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, res)
+}
+
+// handlerModerationWordsSet adds a word to the list, or changes the action of
+// a word that's already on it. The change applies to the next chirp.
+func (cfg *apiConfig) handlerModerationWordsSet(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Word   string `json:"word"`
+		Action string `json:"action"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Couldn't decode parameters", err)
+		return
+	}
+
+	word := moderation.Normalize(params.Word)
+	if word == "" {
+		respondWithError(w, http.StatusUnprocessableEntity, "Word must contain a letter or digit", nil)
+		return
+	}
+
+	if params.Action == "" {
+		params.Action = string(moderation.ActionMask)
+	}
+	action, err := moderation.ParseAction(params.Action)
+	if err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Invalid action", err)
+		return
+	}
+
+	saved, err := cfg.db.UpsertModerationWord(context.Background(), database.UpsertModerationWordParams{
+		Word:   word,
+		Action: string(action),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving moderation word", err)
+		return
+	}
+
+	if err := cfg.moderation.Reload(context.Background()); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error reloading moderation rules", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, ModerationWord{
+		Word:      saved.Word,
+		Action:    moderation.Action(saved.Action),
+		CreatedAt: saved.CreatedAt,
+		UpdatedAt: saved.UpdatedAt,
+	})
+}
+
+func (cfg *apiConfig) handlerModerationWordsDelete(w http.ResponseWriter, r *http.Request) {
+	word := moderation.Normalize(r.PathValue("word"))
+
+	rows, err := cfg.db.DeleteModerationWord(context.Background(), word)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting moderation word", err)
+		return
+	}
+	if rows == 0 {
+		respondWithError(w, http.StatusNotFound, "Word isn't on the moderation list", nil)
+		return
+	}
+
+	if err := cfg.moderation.Reload(context.Background()); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error reloading moderation rules", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
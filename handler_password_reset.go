@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/17xande/bd-chirpy/internal/auth"
+	"github.com/17xande/bd-chirpy/internal/database"
+	"github.com/17xande/bd-chirpy/internal/mailer"
+)
+
+const passwordResetTTL = time.Hour
+
+// sendPasswordResetEmail mails user a single use token for resetting their
+// password. Tokens sent earlier stop working.
+func (cfg *apiConfig) sendPasswordResetEmail(ctx context.Context, user database.User) error {
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return fmt.Errorf("can't make reset token: %w", err)
+	}
+
+	tx, err := cfg.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("can't begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	if err := qtx.DeletePasswordResetTokens(ctx, user.ID); err != nil {
+		return fmt.Errorf("can't delete old reset tokens: %w", err)
+	}
+
+	err = qtx.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().UTC().Add(passwordResetTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("can't store reset token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("can't commit reset token: %w", err)
+	}
+
+	return cfg.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: "Someone asked to reset the password of your Chirpy account. " +
+			"Send this token with your new password to " + cfg.baseURL + "/api/password/reset:\n\n" +
+			token + "\n\n" +
+			"The token expires in 1 hour. If you didn't ask for a reset you can ignore this email.\n",
+	})
+}
+
+// handlerPasswordForgot always responds the same way, whether or not an
+// account uses the email address, so it can't be used to find out who has
+// signed up. The email is sent in the background for the same reason.
+func (cfg *apiConfig) handlerPasswordForgot(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Couldn't decode parameters", err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByEmail(context.Background(), params.Email)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		log.Printf("Can't look up user for password reset: %v", err)
+	default:
+		go func() {
+			if err := cfg.sendPasswordResetEmail(context.Background(), user); err != nil {
+				log.Printf("Can't send password reset email to user %s: %v", user.ID, err)
+			}
+		}()
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handlerPasswordReset sets a new password using a token from
+// handlerPasswordForgot. Every refresh token of the user is revoked, so
+// whoever knew the old password is signed out.
+func (cfg *apiConfig) handlerPasswordReset(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Couldn't decode parameters", err)
+		return
+	}
+
+	if params.Token == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing reset token", nil)
+		return
+	}
+
+	if err := auth.DefaultPasswordPolicy.Validate(params.Password); err != nil {
+		respondWithPasswordError(w, err)
+		return
+	}
+
+	hash, err := auth.HashPassword(params.Password)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error hashing password", err)
+		return
+	}
+
+	tx, err := cfg.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	// Expiry was computed in Go when the token was created, so it is checked
+	// against Go's clock as well, like refresh tokens are.
+	userID, err := qtx.UsePasswordResetToken(context.Background(), database.UsePasswordResetTokenParams{
+		TokenHash: auth.HashToken(params.Token),
+		Now:       time.Now().UTC(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired reset token", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error checking reset token", err)
+		return
+	}
+
+	err = qtx.SetUserPassword(context.Background(), database.SetUserPasswordParams{
+		HashedPassword: hash,
+		ID:             userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating password", err)
+		return
+	}
+
+	if err := qtx.DeletePasswordResetTokens(context.Background(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting reset tokens", err)
+		return
+	}
+
+	if err := qtx.RevokeUserRefreshTokens(context.Background(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error revoking refresh tokens", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error committing password reset", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
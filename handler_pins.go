@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/17xande/bd-chirpy/internal/auth"
+	"github.com/17xande/bd-chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxPinnedChirps    = 3
+	maxPinnedChirpsRed = 10
+)
+
+func pinLimit(user database.User) int64 {
+	if user.IsChirpyRed {
+		return maxPinnedChirpsRed
+	}
+	return maxPinnedChirps
+}
+
+func (cfg *apiConfig) handlerChirpPin(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	id := r.PathValue("id")
+	chirpID, err := uuid.Parse(id)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Can't parse ID: "+id, err)
+		return
+	}
+
+	tx, err := cfg.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	// Locking the user serialises concurrent pins, so they can't both pass
+	// the limit check.
+	user, err := qtx.GetUserForUpdate(context.Background(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting user", err)
+		return
+	}
+
+	chirp, err := qtx.GetChirp(context.Background(), chirpID)
+	if err == nil && (!chirpVisibleTo(chirp, userID) || !chirp.Published) {
+		err = sql.ErrNoRows
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Can't get chirp with this ID", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirp", err)
+		return
+	}
+
+	if chirp.UserID != userID {
+		respondWithError(w, http.StatusForbidden, "Couldn't pin this chirp", nil)
+		return
+	}
+
+	if chirp.PinnedAt.Valid {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	pinned, err := qtx.CountPinnedChirps(context.Background(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error counting pinned chirps", err)
+		return
+	}
+
+	if limit := pinLimit(user); pinned >= limit {
+		respondWithError(w, http.StatusConflict, fmt.Sprintf("You can pin at most %d chirps", limit), nil)
+		return
+	}
+
+	_, err = qtx.PinChirp(context.Background(), database.PinChirpParams{
+		ID:     chirpID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error pinning chirp", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error committing pin", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerChirpUnpin(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	id := r.PathValue("id")
+	chirpID, err := uuid.Parse(id)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Can't parse ID: "+id, err)
+		return
+	}
+
+	_, err = cfg.db.UnpinChirp(context.Background(), database.UnpinChirpParams{
+		ID:     chirpID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error unpinning chirp", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// visiblePinnedChirps returns the pinned chirps of authorID that viewerID may
+// see, most recently pinned first.
+func (cfg *apiConfig) visiblePinnedChirps(ctx context.Context, authorID, viewerID uuid.UUID) ([]database.Chirp, error) {
+	pinned, err := cfg.db.GetPinnedChirps(ctx, authorID)
+	if err != nil {
+		return nil, fmt.Errorf("can't get pinned chirps: %w", err)
+	}
+
+	res := make([]database.Chirp, 0, len(pinned))
+	for _, chirp := range pinned {
+		if chirpVisibleTo(chirp, viewerID) {
+			res = append(res, chirp)
+		}
+	}
+
+	return res, nil
+}
+
+// pinnedPageSize is how many unpinned chirps fit on a page after pinned.
+// Pinned chirps lead the first page and count against its limit; they are
+// only allowed to overflow it when the client asks for fewer chirps than the
+// author has pinned.
+func pinnedPageSize(pageSize int32, pinned []database.Chirp) int32 {
+	return max(pageSize-int32(len(pinned)), 0)
+}
+
+// pageEndCursor returns the cursor of the page after chirps. When pinned
+// chirps filled the first page, chirps is empty and the cursor sorts before
+// every chirp, so the next page starts at the beginning of the listing.
+func pageEndCursor(chirps []database.Chirp, desc bool) pageCursor {
+	if len(chirps) > 0 {
+		last := chirps[len(chirps)-1]
+		return pageCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+	if desc {
+		return pageCursor{CreatedAt: time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC), ID: uuid.Max}
+	}
+	return pageCursor{CreatedAt: time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), ID: uuid.Nil}
+}
+
+// handlerUserChirps lists a user's chirps for their profile, newest first,
+// with pinned chirps at the top of the first page. Pinned chirps are left out
+// of the rest of the listing, so no page repeats them.
+func (cfg *apiConfig) handlerUserChirps(w http.ResponseWriter, r *http.Request) {
+	authorID, err := cfg.resolveUserRef(context.Background(), r.PathValue("id"))
+	if err != nil {
+		respondWithUserRefError(w, err)
+		return
+	}
+
+	pageSize, cursor, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid pagination parameters", err)
+		return
+	}
+
+	viewerID := cfg.viewerID(r)
+
+	pinned := []database.Chirp{}
+	if cursor == nil {
+		pinned, err = cfg.visiblePinnedChirps(context.Background(), authorID, viewerID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Can't get chirps", err)
+			return
+		}
+	}
+	limit := pinnedPageSize(pageSize, pinned)
+
+	// Fetch one extra row to find out whether there is a next page.
+	chirps, err := cfg.db.ListChirpsDesc(context.Background(), database.ListChirpsDescParams{
+		AuthorID:       uuid.NullUUID{UUID: authorID, Valid: true},
+		AfterCreatedAt: cursor.createdAt(),
+		AfterID:        cursor.id(),
+		ViewerID:       uuid.NullUUID{UUID: viewerID, Valid: viewerID != uuid.Nil},
+		ExcludePinned:  true,
+		PageSize:       limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't get chirps", err)
+		return
+	}
+
+	if len(chirps) > int(limit) {
+		chirps = chirps[:limit]
+		setNextPageLink(w, r, pageEndCursor(chirps, true))
+	}
+	chirps = append(pinned, chirps...)
+
+	resChirps, err := cfg.chirpsResponse(context.Background(), chirps, viewerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't build chirps response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, resChirps)
+}
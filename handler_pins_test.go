@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/17xande/bd-chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestPinnedPageSize(t *testing.T) {
+	pinned := []database.Chirp{{ID: uuid.New()}, {ID: uuid.New()}}
+
+	tests := []struct {
+		name     string
+		pageSize int32
+		pinned   []database.Chirp
+		want     int32
+	}{
+		{name: "No pins", pageSize: 20, want: 20},
+		{name: "Pins count against the limit", pageSize: 20, pinned: pinned, want: 18},
+		{name: "Pins fill the page", pageSize: 2, pinned: pinned, want: 0},
+		{name: "More pins than the limit", pageSize: 1, pinned: pinned, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pinnedPageSize(tt.pageSize, tt.pinned); got != tt.want {
+				t.Errorf("pinnedPageSize() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPageEndCursor(t *testing.T) {
+	now := time.Now().UTC()
+	last := database.Chirp{ID: uuid.New(), CreatedAt: now}
+
+	got := pageEndCursor([]database.Chirp{{ID: uuid.New()}, last}, true)
+	if got.ID != last.ID || !got.CreatedAt.Equal(now) {
+		t.Errorf("pageEndCursor() = %v, want the last chirp", got)
+	}
+
+	// An empty page must lead to a cursor before every chirp in either order.
+	desc := pageEndCursor(nil, true)
+	if !desc.CreatedAt.After(now) || desc.ID != uuid.Max {
+		t.Errorf("pageEndCursor(nil, desc) = %v, want one after every chirp", desc)
+	}
+	asc := pageEndCursor(nil, false)
+	if !asc.CreatedAt.Before(now) || asc.ID != uuid.Nil {
+		t.Errorf("pageEndCursor(nil, asc) = %v, want one before every chirp", asc)
+	}
+
+	// The sentinel cursors must survive the round trip through next links.
+	for _, c := range []pageCursor{desc, asc} {
+		decoded, err := decodeCursor(encodeCursor(c))
+		if err != nil || decoded != c {
+			t.Errorf("decodeCursor(encodeCursor(%v)) = %v, %v", c, decoded, err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/17xande/bd-chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Profile is the public view of a user. It's built field by field from
+// database.User so private columns such as the email address and password
+// hash can't leak into it.
+type Profile struct {
+	ID          uuid.UUID `json:"id"`
+	Handle      *string   `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   string    `json:"avatar_url"`
+	CreatedAt   time.Time `json:"created_at"`
+	ChirpCount  int64     `json:"chirp_count"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+}
+
+func (cfg *apiConfig) profileResponse(ctx context.Context, user database.User) (Profile, error) {
+	chirpCount, err := cfg.db.CountUserChirps(ctx, user.ID)
+	if err != nil {
+		return Profile{}, fmt.Errorf("can't count chirps: %w", err)
+	}
+
+	profile := Profile{
+		ID:          user.ID,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		AvatarURL:   user.AvatarUrl,
+		CreatedAt:   user.CreatedAt,
+		ChirpCount:  chirpCount,
+		IsChirpyRed: user.IsChirpyRed,
+	}
+
+	if user.Handle.Valid {
+		handle := user.Handle.String
+		profile.Handle = &handle
+	}
+
+	return profile, nil
+}
+
+func (cfg *apiConfig) handlerUserGet(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.resolveUserRef(context.Background(), r.PathValue("id"))
+	if err != nil {
+		respondWithUserRefError(w, err)
+		return
+	}
+
+	user, err := cfg.db.GetUser(context.Background(), userID)
+	cfg.respondWithProfile(w, user, err)
+}
+
+func (cfg *apiConfig) handlerUserGetByHandle(w http.ResponseWriter, r *http.Request) {
+	user, err := cfg.db.GetUserByHandle(context.Background(), r.PathValue("handle"))
+	cfg.respondWithProfile(w, user, err)
+}
+
+func (cfg *apiConfig) respondWithProfile(w http.ResponseWriter, user database.User, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Can't find this user", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting user", err)
+		return
+	}
+
+	res, err := cfg.profileResponse(context.Background(), user)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't build profile response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, res)
+}
+
+// handlerUserResourceGet routes GET /api/users/{id}/{resource}. ServeMux
+// refuses to register /api/users/by-handle/{handle} next to routes like
+// /api/users/{id}/chirps, because both match /api/users/by-handle/chirps and
+// neither pattern is more specific, so the user routes share one pattern.
+func (cfg *apiConfig) handlerUserResourceGet(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("id") == "by-handle" {
+		r.SetPathValue("handle", r.PathValue("resource"))
+		cfg.handlerUserGetByHandle(w, r)
+		return
+	}
+
+	switch r.PathValue("resource") {
+	case "chirps":
+		cfg.handlerUserChirps(w, r)
+	case "followers":
+		cfg.handlerFollowersGet(w, r)
+	case "following":
+		cfg.handlerFollowingGet(w, r)
+	case "mentions":
+		cfg.handlerUserMentions(w, r)
+	default:
+		respondWithError(w, http.StatusNotFound, "Not found", nil)
+	}
+}
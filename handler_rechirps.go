@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/17xande/bd-chirpy/internal/auth"
+	"github.com/17xande/bd-chirpy/internal/database"
+	"github.com/17xande/bd-chirpy/internal/moderation"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// checkQuoteBody rejects bodies made only of whitespace. Such a body would
+// make a quote that can't be edited, and the unique index on plain rechirps
+// doesn't cover it, so it could be used to rechirp a chirp over and over.
+func checkQuoteBody(body string) error {
+	if body != "" && strings.TrimSpace(body) == "" {
+		return errEmptyQuote
+	}
+	return nil
+}
+
+// handlerRechirpCreate shares another chirp. Without a body it's a plain
+// rechirp, which a user can only make once per chirp; with a body it's a
+// quote chirp.
+func (cfg *apiConfig) handlerRechirpCreate(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	id := r.PathValue("id")
+	chirpID, err := uuid.Parse(id)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Can't parse ID: "+id, err)
+		return
+	}
+
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, http.StatusUnprocessableEntity, "Couldn't decode parameters", err)
+		return
+	}
+
+	if err := checkQuoteBody(params.Body); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't create quote", err)
+		return
+	}
+
+	original, err := cfg.getVisibleChirp(context.Background(), chirpID, userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Can't get chirp with this ID", err)
+		return
+	}
+
+	// Rechirping a plain rechirp shares the chirp it points at instead.
+	if original.IsRechirp && original.Body == "" {
+		if !original.RechirpOfID.Valid {
+			respondWithError(w, http.StatusUnprocessableEntity, "The original chirp has been deleted", nil)
+			return
+		}
+		chirpID = original.RechirpOfID.UUID
+	}
+
+	moderated := moderation.Result{}
+	if params.Body != "" {
+		moderated, err = cfg.validateChirp(params.Body)
+		if err != nil {
+			respondWithError(w, http.StatusUnprocessableEntity, "Can't validate chirp", err)
+			return
+		}
+	}
+
+	tx, err := cfg.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	chirp, err := qtx.CreateRechirp(context.Background(), database.CreateRechirpParams{
+		Body:        moderated.Text,
+		UserID:      userID,
+		RechirpOfID: uuid.NullUUID{UUID: chirpID, Valid: true},
+	})
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		respondWithError(w, http.StatusConflict, "Chirp already rechirped", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating rechirp", err)
+		return
+	}
+
+	if err := processChirp(context.Background(), qtx, chirp, moderated); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error processing chirp", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error committing rechirp", err)
+		return
+	}
+	cfg.linkPreviews.notify()
+
+	res, err := cfg.chirpResponse(context.Background(), chirp, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't build chirp response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, res)
+}
+
+// handlerRechirpDelete undoes a plain rechirp. Quote chirps are removed like
+// any other chirp, through DELETE /api/chirps/{id}.
+func (cfg *apiConfig) handlerRechirpDelete(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	id := r.PathValue("id")
+	chirpID, err := uuid.Parse(id)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Can't parse ID: "+id, err)
+		return
+	}
+
+	_, err = cfg.db.DeleteRechirp(context.Background(), database.DeleteRechirpParams{
+		UserID:      userID,
+		RechirpOfID: uuid.NullUUID{UUID: chirpID, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting rechirp", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckQuoteBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr error
+	}{
+		{
+			name: "Plain rechirp",
+			body: "",
+		},
+		{
+			name: "Quote",
+			body: "look at this",
+		},
+		{
+			name:    "Blank quote",
+			body:    " \t\n",
+			wantErr: errEmptyQuote,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkQuoteBody(tt.body); !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkQuoteBody() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/17xande/bd-chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// ThreadNode is a chirp in a thread with its replies. A chirp the viewer may
+// not see is kept as a tombstone while any of its replies are visible: only
+// its ID, parent and creation time are filled in and Deleted is set.
+type ThreadNode struct {
+	Chirp
+	Deleted bool          `json:"deleted"`
+	Replies []*ThreadNode `json:"replies"`
+}
+
+func (cfg *apiConfig) handlerChirpReplies(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Can't parse ID: "+id, err)
+		return
+	}
+
+	if _, err := cfg.getVisibleChirp(context.Background(), uid, cfg.viewerID(r)); err != nil {
+		respondWithError(w, http.StatusNotFound, "Can't get chirp with this ID", err)
+		return
+	}
+
+	replies, err := cfg.db.GetReplies(context.Background(), uuid.NullUUID{UUID: uid, Valid: true})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't get replies", err)
+		return
+	}
+
+	res, err := cfg.chirpsResponse(context.Background(), replies, cfg.viewerID(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't build chirps response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, res)
+}
+
+// handlerChirpThread returns the whole conversation a chirp belongs to,
+// starting from its root, with every reply nested under its parent.
+func (cfg *apiConfig) handlerChirpThread(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Can't parse ID: "+id, err)
+		return
+	}
+
+	viewerID := cfg.viewerID(r)
+
+	if _, err := cfg.getVisibleChirp(context.Background(), uid, viewerID); err != nil {
+		respondWithError(w, http.StatusNotFound, "Can't get chirp with this ID", err)
+		return
+	}
+
+	thread, err := cfg.db.GetThread(context.Background(), uid)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't get thread", err)
+		return
+	}
+
+	visible := []database.Chirp{}
+	for _, chirp := range thread {
+		if chirpVisibleTo(chirp, viewerID) {
+			visible = append(visible, chirp)
+		}
+	}
+
+	chirps, err := cfg.chirpsResponse(context.Background(), visible, viewerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't build chirps response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, buildThread(thread, chirps))
+}
+
+// buildThread nests the chirps of a thread under their parents, keeping the
+// order of rows among siblings. A parent may come after its replies: a
+// scheduled chirp takes its publish time as its creation time, and its author
+// can reply to it before then. Rows missing from visible become tombstones,
+// and are dropped again when none of their replies are visible either.
+func buildThread(rows []database.Chirp, visible []Chirp) *ThreadNode {
+	byID := make(map[uuid.UUID]Chirp, len(visible))
+	for _, chirp := range visible {
+		byID[chirp.ID] = chirp
+	}
+
+	var root *ThreadNode
+	nodes := make(map[uuid.UUID]*ThreadNode, len(rows))
+
+	for _, row := range rows {
+		chirp, ok := byID[row.ID]
+		node := &ThreadNode{Chirp: chirp, Replies: []*ThreadNode{}}
+		if !ok {
+			node.Chirp = Chirp{ID: row.ID, CreatedAt: row.CreatedAt, Media: []Media{}}
+			if row.ParentID.Valid {
+				parentID := row.ParentID.UUID
+				node.ParentID = &parentID
+			}
+			node.Deleted = true
+		}
+		nodes[row.ID] = node
+	}
+
+	for _, row := range rows {
+		node := nodes[row.ID]
+		if row.ParentID.Valid {
+			if parent, ok := nodes[row.ParentID.UUID]; ok {
+				parent.Replies = append(parent.Replies, node)
+				continue
+			}
+		}
+
+		if root == nil {
+			root = node
+		}
+	}
+
+	if root == nil || !pruneThread(root) {
+		return nil
+	}
+	return root
+}
+
+// pruneThread removes tombstones without visible replies below node. It
+// reports whether node itself is worth keeping.
+func pruneThread(node *ThreadNode) bool {
+	replies := node.Replies[:0]
+	for _, reply := range node.Replies {
+		if pruneThread(reply) {
+			replies = append(replies, reply)
+		}
+	}
+	node.Replies = replies
+
+	return !node.Deleted || len(node.Replies) > 0
+}
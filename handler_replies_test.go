@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/17xande/bd-chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestBuildThread(t *testing.T) {
+	reply := func(parent database.Chirp) database.Chirp {
+		return database.Chirp{
+			ID:       uuid.New(),
+			ParentID: uuid.NullUUID{UUID: parent.ID, Valid: true},
+		}
+	}
+
+	// root (hidden)
+	// ├── a
+	// │   └── b (hidden)
+	// └── c (hidden)
+	//     └── d (hidden)
+	root := database.Chirp{ID: uuid.New()}
+	a := reply(root)
+	b := reply(a)
+	c := reply(root)
+	d := reply(c)
+	rows := []database.Chirp{root, a, b, c, d}
+	visible := []Chirp{chirpFromDB(a)}
+
+	thread := buildThread(rows, visible)
+	if thread == nil {
+		t.Fatal("buildThread() = nil, want a thread")
+	}
+
+	if thread.ID != root.ID || !thread.Deleted {
+		t.Errorf("root = %v (deleted %v), want tombstone of %v", thread.ID, thread.Deleted, root.ID)
+	}
+	if thread.Body != "" {
+		t.Errorf("tombstone has body %q", thread.Body)
+	}
+	if len(thread.Replies) != 1 {
+		t.Fatalf("root has %d replies, want 1", len(thread.Replies))
+	}
+
+	got := thread.Replies[0]
+	if got.ID != a.ID || got.Deleted {
+		t.Errorf("reply = %v (deleted %v), want visible %v", got.ID, got.Deleted, a.ID)
+	}
+	if len(got.Replies) != 0 {
+		t.Errorf("hidden leaf wasn't dropped: %d replies", len(got.Replies))
+	}
+}
+
+func TestBuildThreadParentAfterReply(t *testing.T) {
+	// The root was scheduled and its author replied before it was published,
+	// so the root's creation time is later than the reply's.
+	root := database.Chirp{ID: uuid.New()}
+	a := database.Chirp{ID: uuid.New(), ParentID: uuid.NullUUID{UUID: root.ID, Valid: true}}
+	rows := []database.Chirp{a, root}
+	visible := []Chirp{chirpFromDB(a), chirpFromDB(root)}
+
+	thread := buildThread(rows, visible)
+	if thread == nil || thread.ID != root.ID {
+		t.Fatalf("buildThread() = %v, want root %v", thread, root.ID)
+	}
+	if len(thread.Replies) != 1 || thread.Replies[0].ID != a.ID {
+		t.Errorf("root replies = %v, want [%v]", thread.Replies, a.ID)
+	}
+}
+
+func TestBuildThreadNothingVisible(t *testing.T) {
+	root := database.Chirp{ID: uuid.New()}
+
+	if thread := buildThread([]database.Chirp{root}, nil); thread != nil {
+		t.Errorf("buildThread() = %v, want nil", thread)
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/17xande/bd-chirpy/internal/auth"
+	"github.com/17xande/bd-chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxReportReasonLength = 500
+
+type ChirpReport struct {
+	ID         uuid.UUID  `json:"id"`
+	ReporterID *uuid.UUID `json:"reporter_id"`
+	Reason     string     `json:"reason"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type ReportedChirp struct {
+	Chirp            Chirp         `json:"chirp"`
+	ModerationStatus string        `json:"moderation_status"`
+	Reports          []ChirpReport `json:"reports"`
+}
+
+// flagChirp records a report against a chirp and queues it for review.
+// reporterID is empty for chirps the moderation engine flagged on its own.
+// Hidden chirps stay hidden; reporting them again doesn't make them visible.
+func flagChirp(ctx context.Context, q *database.Queries, chirpID uuid.UUID, reporterID uuid.NullUUID, reason string) error {
+	err := q.CreateChirpReport(ctx, database.CreateChirpReportParams{
+		ChirpID:    chirpID,
+		ReporterID: reporterID,
+		Reason:     reason,
+	})
+	if err != nil {
+		return fmt.Errorf("can't create report: %w", err)
+	}
+
+	if err := q.FlagChirp(ctx, chirpID); err != nil {
+		return fmt.Errorf("can't flag chirp: %w", err)
+	}
+
+	return nil
+}
+
+func (cfg *apiConfig) handlerChirpReport(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	id := r.PathValue("id")
+	chirpID, err := uuid.Parse(id)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Can't parse ID: "+id, err)
+		return
+	}
+
+	type parameters struct {
+		Reason string `json:"reason"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Couldn't decode parameters", err)
+		return
+	}
+
+	params.Reason = strings.TrimSpace(params.Reason)
+	if params.Reason == "" || len(params.Reason) > maxReportReasonLength {
+		respondWithError(w, http.StatusUnprocessableEntity, fmt.Sprintf("reason must be between 1 and %d characters", maxReportReasonLength), nil)
+		return
+	}
+
+	if _, err := cfg.getVisibleChirp(context.Background(), chirpID, userID); err != nil {
+		respondWithError(w, http.StatusNotFound, "Can't get chirp with this ID", err)
+		return
+	}
+
+	tx, err := cfg.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	err = flagChirp(context.Background(), cfg.db.WithTx(tx), chirpID, uuid.NullUUID{UUID: userID, Valid: true}, params.Reason)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error reporting chirp", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error committing report", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handlerAdminReportsGet lists chirps waiting for review together with their
+// open reports. ?status=hidden lists hidden chirps instead.
+func (cfg *apiConfig) handlerAdminReportsGet(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = moderationFlagged
+	}
+	if status != moderationFlagged && status != moderationHidden {
+		respondWithError(w, http.StatusBadRequest, "status must be flagged or hidden", nil)
+		return
+	}
+
+	chirps, err := cfg.db.GetChirpsByModerationStatus(context.Background(), status)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't get reported chirps", err)
+		return
+	}
+
+	resChirps, err := cfg.chirpsResponse(context.Background(), chirps, uuid.Nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't build chirps response", err)
+		return
+	}
+
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		ids = append(ids, chirp.ID)
+	}
+
+	reports, err := cfg.db.GetOpenReports(context.Background(), ids)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't get reports", err)
+		return
+	}
+
+	byChirp := map[uuid.UUID][]ChirpReport{}
+	for _, report := range reports {
+		rep := ChirpReport{
+			ID:        report.ID,
+			Reason:    report.Reason,
+			CreatedAt: report.CreatedAt,
+		}
+		if report.ReporterID.Valid {
+			reporterID := report.ReporterID.UUID
+			rep.ReporterID = &reporterID
+		}
+		byChirp[report.ChirpID] = append(byChirp[report.ChirpID], rep)
+	}
+
+	res := []ReportedChirp{}
+	for i, chirp := range chirps {
+		reported := ReportedChirp{
+			Chirp:            resChirps[i],
+			ModerationStatus: chirp.ModerationStatus,
+			Reports:          byChirp[chirp.ID],
+		}
+		if reported.Reports == nil {
+			reported.Reports = []ChirpReport{}
+		}
+		res = append(res, reported)
+	}
+
+	respondWithJSON(w, http.StatusOK, res)
+}
+
+func (cfg *apiConfig) handlerAdminChirpApprove(w http.ResponseWriter, r *http.Request) {
+	cfg.resolveReportedChirp(w, r, moderationVisible)
+}
+
+func (cfg *apiConfig) handlerAdminChirpHide(w http.ResponseWriter, r *http.Request) {
+	cfg.resolveReportedChirp(w, r, moderationHidden)
+}
+
+// resolveReportedChirp moves a chirp to status and closes its open reports.
+func (cfg *apiConfig) resolveReportedChirp(w http.ResponseWriter, r *http.Request, status string) {
+	id := r.PathValue("id")
+	chirpID, err := uuid.Parse(id)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Can't parse ID: "+id, err)
+		return
+	}
+
+	tx, err := cfg.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	chirp, err := qtx.SetChirpModerationStatus(context.Background(), database.SetChirpModerationStatusParams{
+		ModerationStatus: status,
+		ID:               chirpID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Can't get chirp with this ID", err)
+		return
+	}
+
+	if err := qtx.ResolveChirpReports(context.Background(), chirpID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error resolving reports", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error committing moderation decision", err)
+		return
+	}
+
+	res, err := cfg.chirpResponse(context.Background(), chirp, uuid.Nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't build chirp response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, ReportedChirp{
+		Chirp:            res,
+		ModerationStatus: chirp.ModerationStatus,
+		Reports:          []ChirpReport{},
+	})
+}
+
+func (cfg *apiConfig) handlerAdminChirpDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	chirpID, err := uuid.Parse(id)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Can't parse ID: "+id, err)
+		return
+	}
+
+	// The attachments go with the chirp. Left alone they would be unattached
+	// uploads that anyone with their IDs could fetch again.
+	tx, err := cfg.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	media, err := qtx.DeleteChirpMedia(context.Background(), uuid.NullUUID{UUID: chirpID, Valid: true})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting media", err)
+		return
+	}
+
+	rows, err := qtx.DeleteChirpByID(context.Background(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting chirp", err)
+		return
+	}
+	if rows == 0 {
+		respondWithError(w, http.StatusNotFound, "Can't get chirp with this ID", nil)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error committing chirp deletion", err)
+		return
+	}
+
+	cfg.deleteMediaFiles(context.Background(), media)
+
+	w.WriteHeader(http.StatusNoContent)
+}
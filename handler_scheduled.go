@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/17xande/bd-chirpy/internal/auth"
+	"github.com/17xande/bd-chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxScheduleAhead  = 365 * 24 * time.Hour
+	schedulerInterval = 15 * time.Second
+)
+
+// validatePublishAt checks that a scheduled chirp is due in the future, but
+// not too far in it, and returns the time in UTC like the other timestamps
+// the database stores.
+func validatePublishAt(publishAt, now time.Time) (time.Time, error) {
+	if !publishAt.After(now) {
+		return time.Time{}, errors.New("publish_at must be in the future")
+	}
+	if publishAt.Sub(now) > maxScheduleAhead {
+		return time.Time{}, fmt.Errorf("publish_at must be within %v", maxScheduleAhead)
+	}
+	return publishAt.UTC(), nil
+}
+
+func (cfg *apiConfig) handlerScheduledChirpsGet(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	chirps, err := cfg.db.GetScheduledChirps(context.Background(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't get scheduled chirps", err)
+		return
+	}
+
+	res, err := cfg.chirpsResponse(context.Background(), chirps, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't build chirps response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, res)
+}
+
+// handlerScheduledChirpCancel deletes a scheduled chirp before it's
+// published. Chirps that are already public are deleted through
+// DELETE /api/chirps/{id} instead.
+func (cfg *apiConfig) handlerScheduledChirpCancel(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	id := r.PathValue("id")
+	chirpID, err := uuid.Parse(id)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Can't parse ID: "+id, err)
+		return
+	}
+
+	rows, err := cfg.db.CancelScheduledChirp(context.Background(), database.CancelScheduledChirpParams{
+		ID:     chirpID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error cancelling scheduled chirp", err)
+		return
+	}
+	if rows == 0 {
+		respondWithError(w, http.StatusNotFound, "Can't find a scheduled chirp with this ID", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// runScheduler publishes scheduled chirps once they are due. A published
+// chirp takes its publish time as its creation time, so it shows up in
+// listings where it would have if it had been posted then. Publish times are
+// validated against Go's clock, so due chirps are found with it too.
+func (cfg *apiConfig) runScheduler(ctx context.Context) {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		now := sql.NullTime{Time: time.Now().UTC(), Valid: true}
+		chirps, err := cfg.db.PublishDueChirps(ctx, now)
+		if err != nil {
+			log.Printf("Error publishing scheduled chirps: %v", err)
+		} else if len(chirps) > 0 {
+			log.Printf("Published %d scheduled chirps", len(chirps))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/17xande/bd-chirpy/internal/database"
+)
+
+type SearchResult struct {
+	Chirp
+	Rank float32 `json:"rank"`
+	// Snippet is HTML: the matching parts of the escaped body, with matches
+	// wrapped in <mark>.
+	Snippet string `json:"snippet"`
+}
+
+// searchCursor marks the last result of a page. Results are ordered by rank
+// first, so the cursor carries it along with the (created_at, id) cursor.
+type searchCursor struct {
+	Rank float32
+	pageCursor
+}
+
+func encodeSearchCursor(c searchCursor) string {
+	raw := strconv.FormatFloat(float64(c.Rank), 'g', -1, 32) + "|" + encodeCursor(c.pageCursor)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeSearchCursor(s string) (searchCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return searchCursor{}, fmt.Errorf("can't decode cursor: %w", err)
+	}
+
+	rank, rest, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return searchCursor{}, errors.New("malformed cursor")
+	}
+
+	r, err := strconv.ParseFloat(rank, 32)
+	if err != nil {
+		return searchCursor{}, fmt.Errorf("can't parse cursor rank: %w", err)
+	}
+
+	cursor, err := decodeCursor(rest)
+	if err != nil {
+		return searchCursor{}, err
+	}
+
+	return searchCursor{Rank: float32(r), pageCursor: cursor}, nil
+}
+
+func (cfg *apiConfig) handlerChirpsSearch(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+
+	tsQuery, err := buildSearchQuery(queryParams.Get("q"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid search query", err)
+		return
+	}
+
+	pageSize, err := parseLimit(queryParams)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid pagination parameters", err)
+		return
+	}
+
+	params := database.SearchChirpsParams{
+		Query:    tsQuery,
+		PageSize: pageSize + 1,
+	}
+
+	if c := queryParams.Get("cursor"); c != "" {
+		cursor, err := decodeSearchCursor(c)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid pagination parameters", err)
+			return
+		}
+		params.AfterRank = sql.NullFloat64{Float64: float64(cursor.Rank), Valid: true}
+		params.AfterCreatedAt = cursor.createdAt()
+		params.AfterID = cursor.id()
+	}
+
+	rows, err := cfg.db.SearchChirps(context.Background(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't search chirps", err)
+		return
+	}
+
+	if len(rows) > int(pageSize) {
+		rows = rows[:pageSize]
+		last := rows[len(rows)-1]
+		setNextLink(w, r, encodeSearchCursor(searchCursor{
+			Rank:       last.Rank,
+			pageCursor: pageCursor{CreatedAt: last.CreatedAt, ID: last.ID},
+		}))
+	}
+
+	chirps := make([]database.Chirp, 0, len(rows))
+	for _, row := range rows {
+		chirps = append(chirps, database.Chirp{
+			ID:               row.ID,
+			CreatedAt:        row.CreatedAt,
+			UpdatedAt:        row.UpdatedAt,
+			Body:             row.Body,
+			UserID:           row.UserID,
+			BodySearch:       row.BodySearch,
+			ParentID:         row.ParentID,
+			RechirpOfID:      row.RechirpOfID,
+			IsRechirp:        row.IsRechirp,
+			ModerationStatus: row.ModerationStatus,
+			PublishAt:        row.PublishAt,
+			Published:        row.Published,
+			DeletedAt:        row.DeletedAt,
+			PinnedAt:         row.PinnedAt,
+		})
+	}
+
+	resChirps, err := cfg.chirpsResponse(context.Background(), chirps, cfg.viewerID(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Can't build chirps response", err)
+		return
+	}
+
+	results := []SearchResult{}
+	for i, row := range rows {
+		results = append(results, SearchResult{
+			Chirp:   resChirps[i],
+			Rank:    row.Rank,
+			Snippet: row.Snippet,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, results)
+}
+
+// buildSearchQuery turns user input into a to_tsquery expression. Terms are
+// ANDed together, "quoted words" must appear next to each other and a
+// trailing * makes a term match as a prefix. Everything that isn't a letter
+// or digit is dropped so users can't inject tsquery operators.
+func buildSearchQuery(q string) (string, error) {
+	var clauses []string
+
+	for i, part := range strings.Split(q, `"`) {
+		inPhrase := i%2 == 1
+		if inPhrase {
+			if lexemes := searchLexemes(part); len(lexemes) > 0 {
+				clauses = append(clauses, "("+strings.Join(lexemes, " <-> ")+")")
+			}
+			continue
+		}
+
+		for _, word := range strings.Fields(part) {
+			prefix := strings.HasSuffix(word, "*")
+			lexemes := searchLexemes(word)
+			if len(lexemes) == 0 {
+				continue
+			}
+			if prefix {
+				lexemes[len(lexemes)-1] += ":*"
+			}
+			clauses = append(clauses, strings.Join(lexemes, " & "))
+		}
+	}
+
+	if len(clauses) == 0 {
+		return "", errors.New("query must contain at least one word")
+	}
+
+	return strings.Join(clauses, " & "), nil
+}
+
+func searchLexemes(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSearchCursorRoundTrip(t *testing.T) {
+	want := searchCursor{
+		Rank: 0.0607927,
+		pageCursor: pageCursor{
+			CreatedAt: time.Date(2025, 5, 1, 12, 0, 0, 123456789, time.UTC),
+			ID:        uuid.New(),
+		},
+	}
+
+	got, err := decodeSearchCursor(encodeSearchCursor(want))
+	if err != nil {
+		t.Fatalf("decodeSearchCursor() error = %v", err)
+	}
+	if got.Rank != want.Rank || !got.CreatedAt.Equal(want.CreatedAt) || got.ID != want.ID {
+		t.Errorf("decodeSearchCursor() = %+v, want %+v", got, want)
+	}
+
+	if _, err := decodeSearchCursor(encodeCursor(want.pageCursor)); err == nil {
+		t.Error("decodeSearchCursor() accepted a timeline cursor")
+	}
+}
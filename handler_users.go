@@ -10,6 +10,7 @@ import (
 
 	"github.com/17xande/bd-chirpy/internal/auth"
 	"github.com/17xande/bd-chirpy/internal/database"
+	"github.com/17xande/bd-chirpy/internal/handles"
 	"github.com/google/uuid"
 )
 
@@ -18,14 +19,62 @@ type User struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	Email       string    `json:"email"`
-	Token       string    `json:"token"`
-	IsChirpyRed bool      `json:"is_chirpy_red"`
+	Handle      *string   `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   string    `json:"avatar_url"`
+	// EmailVerifiedAt is nil until the user follows the link mailed to
+	// their current address.
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	Token           string     `json:"token"`
+	IsChirpyRed     bool       `json:"is_chirpy_red"`
+}
+
+// userFromDB copies the columns of a user row that its owner may see. The
+// password hash is never included.
+func userFromDB(user database.User) User {
+	res := User{
+		ID:          user.ID,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+		Email:       user.Email,
+		Handle:      userHandle(user),
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		AvatarURL:   user.AvatarUrl,
+		IsChirpyRed: user.IsChirpyRed,
+	}
+	if user.EmailVerifiedAt.Valid {
+		res.EmailVerifiedAt = &user.EmailVerifiedAt.Time
+	}
+	return res
+}
+
+// respondWithPasswordError reports which rules of the password policy a
+// password failed, so clients can show each one next to the field.
+func respondWithPasswordError(w http.ResponseWriter, err error) {
+	var policyErr *auth.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		respondWithError(w, http.StatusUnprocessableEntity, "Invalid password", err)
+		return
+	}
+
+	type response struct {
+		Message    string                   `json:"message"`
+		Violations []auth.PasswordViolation `json:"violations"`
+	}
+
+	respondWithJSON(w, http.StatusUnprocessableEntity, response{
+		Message:    "Password doesn't meet the password policy",
+		Violations: policyErr.Violations,
+	})
 }
 
 func (cfg *apiConfig) handlerUsersCreate(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Handle   string `json:"handle"`
 	}
 
 	type response struct {
@@ -39,6 +88,16 @@ func (cfg *apiConfig) handlerUsersCreate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if err := validateEmail(params.Email); err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Invalid email", err)
+		return
+	}
+
+	if err := auth.DefaultPasswordPolicy.Validate(params.Password); err != nil {
+		respondWithPasswordError(w, err)
+		return
+	}
+
 	hash, err := auth.HashPassword(params.Password)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error hashing password", nil)
@@ -50,31 +109,41 @@ func (cfg *apiConfig) handlerUsersCreate(w http.ResponseWriter, r *http.Request)
 		HashedPassword: hash,
 	}
 
+	// The handle is optional at signup; users can pick one later.
+	if params.Handle != "" {
+		if err := handles.Validate(params.Handle); err != nil {
+			respondWithError(w, http.StatusUnprocessableEntity, "Invalid handle", err)
+			return
+		}
+		userParams.Handle = sql.NullString{String: params.Handle, Valid: true}
+	}
+
 	u, err := cfg.db.CreateUser(context.Background(), userParams)
+	if isHandleTaken(err) {
+		respondWithError(w, http.StatusConflict, "Handle is already taken", err)
+		return
+	}
+	if isEmailTaken(err) {
+		respondWithError(w, http.StatusConflict, "Email is already in use", err)
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error creating user", err)
 		return
 	}
 
-	res := response{User{
-		ID:        u.ID,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
-		Email:     u.Email,
-	}}
+	cfg.trySendVerificationEmail(u)
+
+	res := response{userFromDB(u)}
 
 	respondWithJSON(w, http.StatusCreated, res)
 }
 
 func (cfg *apiConfig) handlerUsersUpdate(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
-
-	type response struct {
-		User
-		RefreshToken string `json:"refresh_token"`
+		Email           string `json:"email"`
+		Password        string `json:"password"`
+		CurrentPassword string `json:"current_password"`
 	}
 
 	token, err := auth.GetBearerToken(r.Header)
@@ -96,6 +165,27 @@ func (cfg *apiConfig) handlerUsersUpdate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if err := validateEmail(params.Email); err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Invalid email", err)
+		return
+	}
+
+	if err := auth.DefaultPasswordPolicy.Validate(params.Password); err != nil {
+		respondWithPasswordError(w, err)
+		return
+	}
+
+	current, err := cfg.db.GetUser(context.Background(), ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting user", err)
+		return
+	}
+
+	if err := checkCurrentPassword(current, params.CurrentPassword); err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Incorrect current password", err)
+		return
+	}
+
 	hash, err := auth.HashPassword(params.Password)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error hashing password", nil)
@@ -108,38 +198,55 @@ func (cfg *apiConfig) handlerUsersUpdate(w http.ResponseWriter, r *http.Request)
 		HashedPassword: hash,
 	}
 
-	user, err := cfg.db.UpdateUser(context.Background(), userParams)
+	tx, err := cfg.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	user, err := qtx.UpdateUser(context.Background(), userParams)
+	if isEmailTaken(err) {
+		respondWithError(w, http.StatusConflict, "Email is already in use", err)
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error updating user in database", err)
 		return
 	}
 
-	res := response{
-		User: User{
-			ID:          user.ID,
-			CreatedAt:   user.CreatedAt,
-			UpdatedAt:   user.UpdatedAt,
-			Email:       user.Email,
-			Token:       token,
-			IsChirpyRed: user.IsChirpyRed,
-		},
+	// The password always changes here, so every other session ends and the
+	// caller gets a new one.
+	if err := qtx.RevokeUserRefreshTokens(context.Background(), ID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error revoking refresh tokens", err)
+		return
+	}
 
-		// RefreshToken: refreshToken,
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error committing user update", err)
+		return
 	}
 
-	respondWithJSON(w, http.StatusOK, res)
+	if user.Email != current.Email {
+		cfg.trySendVerificationEmail(user)
+	}
 
+	cfg.respondWithSession(w, user)
 }
 
+// handlerUserLogin checks the password. Users with two-factor
+// authentication get an MFA challenge token instead of a session, which
+// handlerUserLoginMFA exchanges for one together with a code.
 func (cfg *apiConfig) handlerUserLogin(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
 		Password string `json:"password"`
 		Email    string `json:"email"`
 	}
 
-	type response struct {
-		User
-		RefreshToken string `json:"refresh_token"`
+	type challengeResponse struct {
+		MFARequired bool   `json:"mfa_required"`
+		MFAToken    string `json:"mfa_token"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -157,6 +264,44 @@ func (cfg *apiConfig) handlerUserLogin(w http.ResponseWriter, r *http.Request) {
 
 	if err := auth.CheckPasswordHash(params.Password, user.HashedPassword); err != nil {
 		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
+		return
+	}
+
+	totp, mfaEnabled, err := cfg.confirmedTOTP(context.Background(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error checking two-factor authentication", err)
+		return
+	}
+
+	if mfaEnabled {
+		// No new challenges while locked; the old ones expire before the
+		// lock is lifted.
+		if mfaLocked(totp) {
+			respondWithError(w, http.StatusTooManyRequests, "Too many wrong codes, try again later", errMFALocked)
+			return
+		}
+
+		challenge, err := auth.MakeMFAChallenge(user.ID, cfg.secret, auth.MFAChallengeTTL)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error generating MFA challenge", err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, challengeResponse{
+			MFARequired: true,
+			MFAToken:    challenge,
+		})
+		return
+	}
+
+	cfg.respondWithSession(w, user)
+}
+
+// respondWithSession signs user in by issuing an access and a refresh token.
+// Every check of the user's credentials must have passed already.
+func (cfg *apiConfig) respondWithSession(w http.ResponseWriter, user database.User) {
+	type response struct {
+		User
+		RefreshToken string `json:"refresh_token"`
 	}
 
 	expires := time.Hour
@@ -164,11 +309,13 @@ func (cfg *apiConfig) handlerUserLogin(w http.ResponseWriter, r *http.Request) {
 	token, err := auth.MakeJWT(user.ID, cfg.secret, expires)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error generating JWT", err)
+		return
 	}
 
 	refreshToken, err := auth.MakeRefreshToken()
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error generating refreshToken", err)
+		return
 	}
 
 	args := database.CreateRefreshTokenParams{
@@ -180,20 +327,14 @@ func (cfg *apiConfig) handlerUserLogin(w http.ResponseWriter, r *http.Request) {
 	_, err = cfg.db.CreateRefreshToken(context.Background(), args)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error storing refreshToken in db", err)
+		return
 	}
 
 	res := response{
-		User: User{
-			ID:          user.ID,
-			CreatedAt:   user.CreatedAt,
-			UpdatedAt:   user.UpdatedAt,
-			Email:       user.Email,
-			Token:       token,
-			IsChirpyRed: user.IsChirpyRed,
-		},
-
+		User:         userFromDB(user),
 		RefreshToken: refreshToken,
 	}
+	res.Token = token
 
 	respondWithJSON(w, http.StatusOK, res)
 }
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"unicode/utf8"
+
+	"github.com/17xande/bd-chirpy/internal/auth"
+	"github.com/17xande/bd-chirpy/internal/database"
+	"github.com/lib/pq"
+)
+
+const (
+	emailIndex           = "users_email_key"
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+	maxAvatarURLLength   = 2048
+)
+
+// isEmailTaken reports whether err is the unique constraint on email
+// addresses rejecting a write.
+func isEmailTaken(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == emailIndex
+}
+
+func validateEmail(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return fmt.Errorf("invalid email address: %q", email)
+	}
+	return nil
+}
+
+func validateProfileText(field, value string, maxLength int) error {
+	if utf8.RuneCountInString(value) > maxLength {
+		return fmt.Errorf("%s must be at most %d characters", field, maxLength)
+	}
+	return nil
+}
+
+func validateAvatarURL(avatarURL string) error {
+	if avatarURL == "" {
+		return nil
+	}
+	if len(avatarURL) > maxAvatarURLLength {
+		return fmt.Errorf("avatar_url must be at most %d characters", maxAvatarURLLength)
+	}
+	u, err := url.Parse(avatarURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("avatar_url must be an http or https URL")
+	}
+	return nil
+}
+
+// checkCurrentPassword confirms a change to the email address or password.
+// An access token alone isn't enough, so a stolen one can't be used to take
+// over the account.
+func checkCurrentPassword(user database.User, password string) error {
+	if password == "" {
+		return errors.New("current_password is required to change email or password")
+	}
+	return auth.CheckPasswordHash(password, user.HashedPassword)
+}
+
+// handlerUsersMePatch updates the fields of the signed in user that are
+// present in the request and leaves the rest alone. Changing the email
+// address or password requires the current password.
+func (cfg *apiConfig) handlerUsersMePatch(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user ID from token", err)
+		return
+	}
+
+	type parameters struct {
+		Email           *string `json:"email"`
+		Password        *string `json:"password"`
+		CurrentPassword string  `json:"current_password"`
+		DisplayName     *string `json:"display_name"`
+		Bio             *string `json:"bio"`
+		AvatarURL       *string `json:"avatar_url"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Couldn't decode parameters", err)
+		return
+	}
+
+	update := database.UpdateUserProfileParams{ID: userID}
+
+	if params.Email != nil {
+		if err := validateEmail(*params.Email); err != nil {
+			respondWithError(w, http.StatusUnprocessableEntity, "Invalid email", err)
+			return
+		}
+		update.Email = sql.NullString{String: *params.Email, Valid: true}
+	}
+
+	if params.Password != nil {
+		if err := auth.DefaultPasswordPolicy.Validate(*params.Password); err != nil {
+			respondWithPasswordError(w, err)
+			return
+		}
+	}
+
+	if params.DisplayName != nil {
+		if err := validateProfileText("display_name", *params.DisplayName, maxDisplayNameLength); err != nil {
+			respondWithError(w, http.StatusUnprocessableEntity, "Invalid display_name", err)
+			return
+		}
+		update.DisplayName = sql.NullString{String: *params.DisplayName, Valid: true}
+	}
+
+	if params.Bio != nil {
+		if err := validateProfileText("bio", *params.Bio, maxBioLength); err != nil {
+			respondWithError(w, http.StatusUnprocessableEntity, "Invalid bio", err)
+			return
+		}
+		update.Bio = sql.NullString{String: *params.Bio, Valid: true}
+	}
+
+	if params.AvatarURL != nil {
+		if err := validateAvatarURL(*params.AvatarURL); err != nil {
+			respondWithError(w, http.StatusUnprocessableEntity, "Invalid avatar_url", err)
+			return
+		}
+		update.AvatarUrl = sql.NullString{String: *params.AvatarURL, Valid: true}
+	}
+
+	var current database.User
+	if params.Email != nil || params.Password != nil {
+		current, err = cfg.db.GetUser(context.Background(), userID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error getting user", err)
+			return
+		}
+
+		if err := checkCurrentPassword(current, params.CurrentPassword); err != nil {
+			respondWithError(w, http.StatusUnauthorized, "Incorrect current password", err)
+			return
+		}
+	}
+
+	if params.Password != nil {
+		hash, err := auth.HashPassword(*params.Password)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error hashing password", err)
+			return
+		}
+		update.HashedPassword = sql.NullString{String: hash, Valid: true}
+	}
+
+	tx, err := cfg.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	user, err := qtx.UpdateUserProfile(context.Background(), update)
+	if isEmailTaken(err) {
+		respondWithError(w, http.StatusConflict, "Email is already in use", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating user", err)
+		return
+	}
+
+	// A new password ends every other session, which is usually why users
+	// change it.
+	if params.Password != nil {
+		if err := qtx.RevokeUserRefreshTokens(context.Background(), userID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error revoking refresh tokens", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error committing user update", err)
+		return
+	}
+
+	if params.Email != nil && user.Email != current.Email {
+		cfg.trySendVerificationEmail(user)
+	}
+
+	if params.Password != nil {
+		cfg.respondWithSession(w, user)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, userFromDB(user))
+}
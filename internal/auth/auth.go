@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -18,7 +19,13 @@ type TokenType string
 
 var ErrNoAuthHeaderIncluded = errors.New("no auth header included in request")
 
-const TokenTypeAccess TokenType = "chirpy-access"
+const (
+	TokenTypeAccess TokenType = "chirpy-access"
+	// TokenTypeMFAChallenge is issued after a correct password when the user
+	// has two-factor authentication enabled. It only proves the first
+	// factor, so ValidateJWT rejects it.
+	TokenTypeMFAChallenge TokenType = "chirpy-mfa-challenge"
+)
 
 func HashPassword(password string) (string, error) {
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -31,9 +38,27 @@ func CheckPasswordHash(password, hash string) error {
 }
 
 func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	return makeToken(TokenTypeAccess, userID, tokenSecret, expiresIn)
+}
+
+func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	return validateToken(TokenTypeAccess, tokenString, tokenSecret)
+}
+
+// MakeMFAChallenge returns a token that can be exchanged for an access token
+// together with a second factor.
+func MakeMFAChallenge(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	return makeToken(TokenTypeMFAChallenge, userID, tokenSecret, expiresIn)
+}
+
+func ValidateMFAChallenge(tokenString, tokenSecret string) (uuid.UUID, error) {
+	return validateToken(TokenTypeMFAChallenge, tokenString, tokenSecret)
+}
+
+func makeToken(tokenType TokenType, userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
 	signinKey := []byte(tokenSecret)
 	claims := jwt.RegisteredClaims{
-		Issuer:    string(TokenTypeAccess),
+		Issuer:    string(tokenType),
 		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
 		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
 		Subject:   userID.String(),
@@ -42,7 +67,7 @@ func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (str
 	return token.SignedString(signinKey)
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+func validateToken(tokenType TokenType, tokenString, tokenSecret string) (uuid.UUID, error) {
 	claims := jwt.RegisteredClaims{}
 	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (any, error) {
 		return []byte(tokenSecret), nil
@@ -61,7 +86,7 @@ func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
 		return uuid.Nil, fmt.Errorf("can't get claim issuer: %w", err)
 	}
 
-	if issuer != string(TokenTypeAccess) {
+	if issuer != string(tokenType) {
 		return uuid.Nil, errors.New("invalid issuer")
 	}
 
@@ -93,6 +118,15 @@ func MakeRefreshToken() (string, error) {
 	return token, err
 }
 
+// HashToken returns the hex encoded SHA-256 hash of a random token. Tokens
+// sent to users are stored hashed, so a leaked table can't be used to
+// redeem them. Unlike passwords they're long and random, so a fast hash is
+// enough.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func GetAPIKey(headers http.Header) (string, error) {
 	head := headers.Get("Authorization")
 	if head == "" {
@@ -65,6 +65,7 @@ func TestCheckPasswordHash(t *testing.T) {
 func TestValidateJWT(t *testing.T) {
 	userID := uuid.New()
 	validToken, _ := MakeJWT(userID, "secret", time.Hour)
+	challengeToken, _ := MakeMFAChallenge(userID, "secret", time.Hour)
 
 	tests := []struct {
 		name        string
@@ -94,6 +95,13 @@ func TestValidateJWT(t *testing.T) {
 			wantUserID:  uuid.Nil,
 			wantErr:     true,
 		},
+		{
+			name:        "MFA challenge token",
+			tokenString: challengeToken,
+			tokenSecret: "secret",
+			wantUserID:  uuid.Nil,
+			wantErr:     true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -155,3 +163,37 @@ func TestBearerToken(t *testing.T) {
 		})
 	}
 }
+
+func TestHashToken(t *testing.T) {
+	got := HashToken("abc")
+	want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if got != want {
+		t.Errorf("HashToken() = %v, want %v", got, want)
+	}
+	if HashToken("abd") == got {
+		t.Error("HashToken() returned the same hash for different tokens")
+	}
+}
+
+func TestValidateMFAChallenge(t *testing.T) {
+	userID := uuid.New()
+
+	challengeToken, _ := MakeMFAChallenge(userID, "secret", time.Hour)
+	gotUserID, err := ValidateMFAChallenge(challengeToken, "secret")
+	if err != nil {
+		t.Fatalf("ValidateMFAChallenge() error = %v", err)
+	}
+	if gotUserID != userID {
+		t.Errorf("ValidateMFAChallenge() gotUserID = %v, want %v", gotUserID, userID)
+	}
+
+	accessToken, _ := MakeJWT(userID, "secret", time.Hour)
+	if _, err := ValidateMFAChallenge(accessToken, "secret"); err == nil {
+		t.Error("ValidateMFAChallenge() accepted an access token")
+	}
+
+	expiredToken, _ := MakeMFAChallenge(userID, "secret", -time.Minute)
+	if _, err := ValidateMFAChallenge(expiredToken, "secret"); err == nil {
+		t.Error("ValidateMFAChallenge() accepted an expired token")
+	}
+}
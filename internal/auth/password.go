@@ -0,0 +1,146 @@
+package auth
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxPasswordBytes is the longest password bcrypt can hash. Anything past
+// it would be silently ignored, so longer passwords are rejected instead.
+const MaxPasswordBytes = 72
+
+// Password rules reported in PasswordViolation.Rule.
+const (
+	RuleMinLength = "min_length"
+	RuleMaxLength = "max_length"
+	RuleClasses   = "character_classes"
+	RuleCommon    = "common_password"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+var commonPasswords = parseCommonPasswords(commonPasswordList)
+
+// PasswordPolicy describes what a password must look like before it's
+// hashed and stored.
+type PasswordPolicy struct {
+	// MinLength is the minimum length in characters.
+	MinLength int
+	// MinClasses is how many of lower case letters, upper case letters,
+	// digits and symbols the password must contain.
+	MinClasses int
+	// RejectCommon rejects passwords from the bundled list of commonly used
+	// and breached passwords, compared case-insensitively.
+	RejectCommon bool
+}
+
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:    10,
+	MinClasses:   2,
+	RejectCommon: true,
+}
+
+// PasswordViolation is a single rule a password failed.
+type PasswordViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError lists every rule a password failed, so users can fix
+// them all at once.
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Message)
+	}
+	return "password doesn't meet the policy: " + strings.Join(messages, "; ")
+}
+
+// Validate returns a *PasswordPolicyError if password breaks any rule of
+// the policy.
+func (p PasswordPolicy) Validate(password string) error {
+	var violations []PasswordViolation
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		violations = append(violations, PasswordViolation{
+			Rule:    RuleMinLength,
+			Message: fmt.Sprintf("must be at least %d characters", p.MinLength),
+		})
+	}
+
+	if len(password) > MaxPasswordBytes {
+		violations = append(violations, PasswordViolation{
+			Rule:    RuleMaxLength,
+			Message: fmt.Sprintf("must be at most %d bytes", MaxPasswordBytes),
+		})
+	}
+
+	if characterClasses(password) < p.MinClasses {
+		violations = append(violations, PasswordViolation{
+			Rule:    RuleClasses,
+			Message: fmt.Sprintf("must contain at least %d of lower case letters, upper case letters, digits and symbols", p.MinClasses),
+		})
+	}
+
+	if p.RejectCommon && IsCommonPassword(password) {
+		violations = append(violations, PasswordViolation{
+			Rule:    RuleCommon,
+			Message: "is too common",
+		})
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// IsCommonPassword reports whether password is on the bundled list of
+// common passwords.
+func IsCommonPassword(password string) bool {
+	return commonPasswords[strings.ToLower(password)]
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	n := 0
+	for _, ok := range []bool{lower, upper, digit, symbol} {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+func parseCommonPasswords(list string) map[string]bool {
+	passwords := map[string]bool{}
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		passwords[strings.ToLower(line)] = true
+	}
+	return passwords
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		password  string
+		wantRules []string
+	}{
+		{
+			name:      "Strong password",
+			password:  "correct horse battery staple",
+			wantRules: nil,
+		},
+		{
+			name:      "Mixed classes",
+			password:  "Tr0ub4dor&3",
+			wantRules: nil,
+		},
+		{
+			name:      "Empty password",
+			password:  "",
+			wantRules: []string{RuleMinLength, RuleClasses},
+		},
+		{
+			name:      "Too short",
+			password:  "aB3$",
+			wantRules: []string{RuleMinLength},
+		},
+		{
+			name:      "Short multibyte characters count once",
+			password:  "äöüäöüäöü1",
+			wantRules: nil,
+		},
+		{
+			name:      "Single class",
+			password:  "abcdefghijkl",
+			wantRules: []string{RuleClasses},
+		},
+		{
+			name:      "Longer than bcrypt allows",
+			password:  strings.Repeat("aB3", 25),
+			wantRules: []string{RuleMaxLength},
+		},
+		{
+			name:      "Common password",
+			password:  "password123",
+			wantRules: []string{RuleCommon},
+		},
+		{
+			name:      "Common password in other case",
+			password:  "PASSWORD123",
+			wantRules: []string{RuleCommon},
+		},
+		{
+			name:      "Several rules at once",
+			password:  "qwerty",
+			wantRules: []string{RuleMinLength, RuleClasses, RuleCommon},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DefaultPasswordPolicy.Validate(tt.password)
+			if tt.wantRules == nil {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var policyErr *PasswordPolicyError
+			if !errors.As(err, &policyErr) {
+				t.Fatalf("Validate() error = %v, want *PasswordPolicyError", err)
+			}
+
+			var rules []string
+			for _, v := range policyErr.Violations {
+				rules = append(rules, v.Rule)
+			}
+			if !reflect.DeepEqual(rules, tt.wantRules) {
+				t.Errorf("Validate() rules = %v, want %v", rules, tt.wantRules)
+			}
+		})
+	}
+}
+
+func TestIsCommonPassword(t *testing.T) {
+	if !IsCommonPassword("iloveyou") {
+		t.Error("IsCommonPassword(\"iloveyou\") = false, want true")
+	}
+	if IsCommonPassword("# Commonly used and breached passwords, one per line. Only passwords that") {
+		t.Error("IsCommonPassword() matched a comment line")
+	}
+	if IsCommonPassword("") {
+		t.Error("IsCommonPassword(\"\") = true, want false")
+	}
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters from RFC 6238. They're the defaults every authenticator
+// app supports, so they aren't configurable.
+const (
+	totpPeriod = 30
+	totpDigits = 6
+	// totpSkew is how many periods before and after the current one are
+	// accepted, to allow for clock drift and slow typing.
+	totpSkew = 1
+)
+
+const (
+	recoveryCodeCount = 10
+	recoveryCodeBytes = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a random 160 bit secret, base32 encoded the way
+// authenticator apps expect it.
+func GenerateTOTPSecret() (string, error) {
+	key := make([]byte, 20)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(key), nil
+}
+
+// TOTPURI returns the otpauth:// URI that authenticator apps import, usually
+// from a QR code.
+func TOTPURI(issuer, account, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(totpDigits))
+	v.Set("period", fmt.Sprint(totpPeriod))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: v.Encode(),
+	}
+	return u.String()
+}
+
+// TOTPStep returns the number of the time step t falls in.
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / totpPeriod
+}
+
+// ValidateTOTP checks code against the steps around t and returns the step
+// it matched. Callers should store the step and reject codes for it or any
+// earlier step, so a code can't be used twice.
+func ValidateTOTP(secret, code string, t time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	now := TOTPStep(t)
+	for step := now - totpSkew; step <= now+totpSkew; step++ {
+		want := hotp(key, uint64(step), totpDigits)
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// hotp computes an HOTP value as described in RFC 4226, section 5.3.
+func hotp(key []byte, counter uint64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for range digits {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
+
+// GenerateRecoveryCodes returns single use codes that sign a user in when
+// they've lost their authenticator. Codes look like "abcd-efgh-ijkl-mnop".
+func GenerateRecoveryCodes() ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		key := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(key))
+
+		var parts []string
+		for len(code) > 0 {
+			parts = append(parts, code[:4])
+			code = code[4:]
+		}
+		codes = append(codes, strings.Join(parts, "-"))
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode strips the separators and case users may type
+// differently, so a code can be compared by its hash.
+func NormalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(code))
+}
+
+// MFAChallengeTTL is how long a token from MakeMFAChallenge can be exchanged
+// for a session.
+const MFAChallengeTTL = 5 * time.Minute
+
+// Lockout limits how many wrong second factors can be tried before further
+// attempts are refused for a while.
+type Lockout struct {
+	MaxAttempts int32
+	// Duration must be longer than MFAChallengeTTL, so every challenge
+	// issued before the lock has expired by the time it's lifted.
+	Duration time.Duration
+}
+
+var DefaultMFALockout = Lockout{
+	MaxAttempts: 5,
+	Duration:    15 * time.Minute,
+}
+
+// Locked reports whether a lock that ends at lockedUntil is still active.
+// A zero lockedUntil means there's no lock.
+func Locked(lockedUntil, now time.Time) bool {
+	return now.Before(lockedUntil)
+}
+
+// LockUntil returns when the lock ends if failures consecutive wrong
+// attempts should lock the second factor, and false otherwise.
+func (l Lockout) LockUntil(failures int32, now time.Time) (time.Time, bool) {
+	if failures < l.MaxAttempts {
+		return time.Time{}, false
+	}
+	return now.Add(l.Duration), true
+}
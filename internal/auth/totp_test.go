@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// The SHA-1 test vectors from RFC 6238, appendix B.
+func TestHOTPRFC6238Vectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, tt := range tests {
+		step := TOTPStep(time.Unix(tt.unix, 0))
+		if got := hotp(key, uint64(step), 8); got != tt.want {
+			t.Errorf("hotp() at %d = %v, want %v", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	// base32 of "12345678901234567890"
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	now := time.Unix(1111111111, 0)
+	code := hotp([]byte("12345678901234567890"), uint64(TOTPStep(now)), totpDigits)
+
+	tests := []struct {
+		name     string
+		code     string
+		at       time.Time
+		wantStep int64
+		wantOK   bool
+	}{
+		{
+			name:     "Current step",
+			code:     code,
+			at:       now,
+			wantStep: TOTPStep(now),
+			wantOK:   true,
+		},
+		{
+			name:     "Previous step is within skew",
+			code:     code,
+			at:       now.Add(totpPeriod * time.Second),
+			wantStep: TOTPStep(now),
+			wantOK:   true,
+		},
+		{
+			name:   "Too old",
+			code:   code,
+			at:     now.Add(2 * totpPeriod * time.Second),
+			wantOK: false,
+		},
+		{
+			name:   "Wrong code",
+			code:   "000000",
+			at:     now,
+			wantOK: code == "000000",
+		},
+		{
+			name:   "Wrong length",
+			code:   code + "0",
+			at:     now,
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(secret, tt.code, tt.at)
+			if ok != tt.wantOK {
+				t.Fatalf("ValidateTOTP() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && step != tt.wantStep {
+				t.Errorf("ValidateTOTP() step = %v, want %v", step, tt.wantStep)
+			}
+		})
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret() error = %v", err)
+	}
+	if len(secret) != 32 {
+		t.Errorf("GenerateTOTPSecret() = %q, want 32 base32 characters", secret)
+	}
+
+	code := hotp(mustDecodeSecret(t, secret), uint64(TOTPStep(time.Now())), totpDigits)
+	if _, ok := ValidateTOTP(secret, code, time.Now()); !ok {
+		t.Error("ValidateTOTP() rejected a code for a generated secret")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	got := TOTPURI("Chirpy", "user@example.com", "JBSWY3DPEHPK3PXP")
+	want := "otpauth://totp/Chirpy:user@example.com?algorithm=SHA1&digits=6&issuer=Chirpy&period=30&secret=JBSWY3DPEHPK3PXP"
+	if got != want {
+		t.Errorf("TOTPURI() = %v, want %v", got, want)
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes() error = %v", err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("GenerateRecoveryCodes() returned %d codes, want %d", len(codes), recoveryCodeCount)
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 19 || strings.Count(code, "-") != 3 {
+			t.Errorf("recovery code %q doesn't look like xxxx-xxxx-xxxx-xxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("recovery code %q repeated", code)
+		}
+		seen[code] = true
+
+		typed := strings.ToUpper(strings.ReplaceAll(code, "-", " "))
+		if NormalizeRecoveryCode(typed) != NormalizeRecoveryCode(code) {
+			t.Errorf("NormalizeRecoveryCode(%q) != NormalizeRecoveryCode(%q)", typed, code)
+		}
+	}
+}
+
+func mustDecodeSecret(t *testing.T, secret string) []byte {
+	t.Helper()
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("can't decode secret: %v", err)
+	}
+	return key
+}
+
+func TestLockout(t *testing.T) {
+	l := DefaultMFALockout
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for failures := int32(1); failures < l.MaxAttempts; failures++ {
+		if _, lock := l.LockUntil(failures, now); lock {
+			t.Fatalf("LockUntil(%d) locked before %d attempts", failures, l.MaxAttempts)
+		}
+	}
+
+	until, lock := l.LockUntil(l.MaxAttempts, now)
+	if !lock {
+		t.Fatalf("LockUntil(%d) didn't lock", l.MaxAttempts)
+	}
+	if !Locked(until, now) {
+		t.Error("Locked() = false right after locking")
+	}
+	if !Locked(until, now.Add(MFAChallengeTTL)) {
+		t.Error("lock ended before challenges issued before it expired")
+	}
+	if Locked(until, until) {
+		t.Error("Locked() = true once the lock ended")
+	}
+	if Locked(time.Time{}, now) {
+		t.Error("Locked() = true without a lock")
+	}
+}
@@ -7,23 +7,151 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const cancelScheduledChirp = `-- name: CancelScheduledChirp :execrows
+delete from chirps
+where id = $1 and user_id = $2 and not published
+`
+
+type CancelScheduledChirpParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) CancelScheduledChirp(ctx context.Context, arg CancelScheduledChirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, cancelScheduledChirp, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const countPinnedChirps = `-- name: CountPinnedChirps :one
+select count(*) from chirps
+where user_id = $1 and pinned_at is not null and deleted_at is null
+`
+
+func (q *Queries) CountPinnedChirps(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPinnedChirps, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countReplies = `-- name: CountReplies :many
+select parent_id, count(*) as reply_count from chirps
+where moderation_status <> 'hidden'
+  and published
+  and deleted_at is null
+  and parent_id = any($1::uuid[])
+group by parent_id
+`
+
+type CountRepliesRow struct {
+	ParentID   uuid.NullUUID
+	ReplyCount int64
+}
+
+func (q *Queries) CountReplies(ctx context.Context, chirpIds []uuid.UUID) ([]CountRepliesRow, error) {
+	rows, err := q.db.QueryContext(ctx, countReplies, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountRepliesRow
+	for rows.Next() {
+		var i CountRepliesRow
+		if err := rows.Scan(
+			&i.ParentID,
+			&i.ReplyCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countUserChirps = `-- name: CountUserChirps :one
+select count(*) from chirps
+where user_id = $1
+  and published and deleted_at is null
+  and moderation_status <> 'hidden'
+`
+
+func (q *Queries) CountUserChirps(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUserChirps, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createChirps = `-- name: CreateChirps :one
-insert into chirps(id, created_at, updated_at, body, user_id)
-values (gen_random_uuid(), now(), now(), $1, $2)
-returning id, created_at, updated_at, body, user_id
+insert into chirps(id, created_at, updated_at, body, user_id, parent_id, publish_at, published)
+values (gen_random_uuid(), now(), now(), $1, $2, $3, $4, $4::timestamp is null)
+returning id, created_at, updated_at, body, user_id, body_search, parent_id, rechirp_of_id, is_rechirp, moderation_status, publish_at, published, deleted_at, pinned_at
 `
 
 type CreateChirpsParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body      string
+	UserID    uuid.UUID
+	ParentID  uuid.NullUUID
+	PublishAt sql.NullTime
 }
 
 func (q *Queries) CreateChirps(ctx context.Context, arg CreateChirpsParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirps, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirps,
+		arg.Body,
+		arg.UserID,
+		arg.ParentID,
+		arg.PublishAt,
+	)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.BodySearch,
+		&i.ParentID,
+		&i.RechirpOfID,
+		&i.IsRechirp,
+		&i.ModerationStatus,
+		&i.PublishAt,
+		&i.Published,
+		&i.DeletedAt,
+		&i.PinnedAt,
+	)
+	return i, err
+}
+
+const createRechirp = `-- name: CreateRechirp :one
+insert into chirps(id, created_at, updated_at, body, user_id, rechirp_of_id, is_rechirp)
+values (gen_random_uuid(), now(), now(), $1, $2, $3, true)
+returning id, created_at, updated_at, body, user_id, body_search, parent_id, rechirp_of_id, is_rechirp, moderation_status, publish_at, published, deleted_at, pinned_at
+`
+
+type CreateRechirpParams struct {
+	Body        string
+	UserID      uuid.UUID
+	RechirpOfID uuid.NullUUID
+}
+
+func (q *Queries) CreateRechirp(ctx context.Context, arg CreateRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createRechirp, arg.Body, arg.UserID, arg.RechirpOfID)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -31,30 +159,63 @@ func (q *Queries) CreateChirps(ctx context.Context, arg CreateChirpsParams) (Chi
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.BodySearch,
+		&i.ParentID,
+		&i.RechirpOfID,
+		&i.IsRechirp,
+		&i.ModerationStatus,
+		&i.PublishAt,
+		&i.Published,
+		&i.DeletedAt,
+		&i.PinnedAt,
 	)
 	return i, err
 }
 
-const deleteChirp = `-- name: DeleteChirp :execrows
+const deleteChirpByID = `-- name: DeleteChirpByID :execrows
 delete from chirps
-where id = $1 and user_id = $2
+where id = $1
 `
 
-type DeleteChirpParams struct {
-	ID     uuid.UUID
-	UserID uuid.UUID
+func (q *Queries) DeleteChirpByID(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteChirpByID, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteRechirp = `-- name: DeleteRechirp :execrows
+delete from chirps
+where user_id = $1 and rechirp_of_id = $2 and is_rechirp and body = ''
+`
+
+type DeleteRechirpParams struct {
+	UserID      uuid.UUID
+	RechirpOfID uuid.NullUUID
 }
 
-func (q *Queries) DeleteChirp(ctx context.Context, arg DeleteChirpParams) (int64, error) {
-	result, err := q.db.ExecContext(ctx, deleteChirp, arg.ID, arg.UserID)
+func (q *Queries) DeleteRechirp(ctx context.Context, arg DeleteRechirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteRechirp, arg.UserID, arg.RechirpOfID)
 	if err != nil {
 		return 0, err
 	}
 	return result.RowsAffected()
 }
 
+const flagChirp = `-- name: FlagChirp :exec
+update chirps
+set moderation_status = 'flagged'
+where id = $1 and moderation_status = 'visible'
+`
+
+func (q *Queries) FlagChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, flagChirp, id)
+	return err
+}
+
 const getChirp = `-- name: GetChirp :one
-select id, created_at, updated_at, body, user_id from chirps
+select id, created_at, updated_at, body, user_id, body_search, parent_id, rechirp_of_id, is_rechirp, moderation_status, publish_at, published, deleted_at, pinned_at from chirps
 where id = $1
 `
 
@@ -67,12 +228,49 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.BodySearch,
+		&i.ParentID,
+		&i.RechirpOfID,
+		&i.IsRechirp,
+		&i.ModerationStatus,
+		&i.PublishAt,
+		&i.Published,
+		&i.DeletedAt,
+		&i.PinnedAt,
+	)
+	return i, err
+}
+
+const getChirpForUpdate = `-- name: GetChirpForUpdate :one
+select id, created_at, updated_at, body, user_id, body_search, parent_id, rechirp_of_id, is_rechirp, moderation_status, publish_at, published, deleted_at, pinned_at from chirps
+where id = $1
+for update
+`
+
+func (q *Queries) GetChirpForUpdate(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpForUpdate, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.BodySearch,
+		&i.ParentID,
+		&i.RechirpOfID,
+		&i.IsRechirp,
+		&i.ModerationStatus,
+		&i.PublishAt,
+		&i.Published,
+		&i.DeletedAt,
+		&i.PinnedAt,
 	)
 	return i, err
 }
 
 const getChirps = `-- name: GetChirps :many
-select id, created_at, updated_at, body, user_id from chirps
+select id, created_at, updated_at, body, user_id, body_search, parent_id, rechirp_of_id, is_rechirp, moderation_status, publish_at, published, deleted_at, pinned_at from chirps
 order by created_at
 `
 
@@ -91,6 +289,15 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.BodySearch,
+			&i.ParentID,
+			&i.RechirpOfID,
+			&i.IsRechirp,
+			&i.ModerationStatus,
+			&i.PublishAt,
+			&i.Published,
+			&i.DeletedAt,
+			&i.PinnedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -106,7 +313,7 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirpsByAuthor = `-- name: GetChirpsByAuthor :many
-select id, created_at, updated_at, body, user_id from chirps
+select id, created_at, updated_at, body, user_id, body_search, parent_id, rechirp_of_id, is_rechirp, moderation_status, publish_at, published, deleted_at, pinned_at from chirps
 where user_id = $1
 order by created_at
 `
@@ -126,6 +333,256 @@ func (q *Queries) GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Ch
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.BodySearch,
+			&i.ParentID,
+			&i.RechirpOfID,
+			&i.IsRechirp,
+			&i.ModerationStatus,
+			&i.PublishAt,
+			&i.Published,
+			&i.DeletedAt,
+			&i.PinnedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+select id, created_at, updated_at, body, user_id, body_search, parent_id, rechirp_of_id, is_rechirp, moderation_status, publish_at, published, deleted_at, pinned_at from chirps
+where id = any($1::uuid[])
+`
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.BodySearch,
+			&i.ParentID,
+			&i.RechirpOfID,
+			&i.IsRechirp,
+			&i.ModerationStatus,
+			&i.PublishAt,
+			&i.Published,
+			&i.DeletedAt,
+			&i.PinnedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByModerationStatus = `-- name: GetChirpsByModerationStatus :many
+select id, created_at, updated_at, body, user_id, body_search, parent_id, rechirp_of_id, is_rechirp, moderation_status, publish_at, published, deleted_at, pinned_at from chirps
+where moderation_status = $1 and deleted_at is null
+order by created_at, id
+`
+
+func (q *Queries) GetChirpsByModerationStatus(ctx context.Context, moderationStatus string) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByModerationStatus, moderationStatus)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.BodySearch,
+			&i.ParentID,
+			&i.RechirpOfID,
+			&i.IsRechirp,
+			&i.ModerationStatus,
+			&i.PublishAt,
+			&i.Published,
+			&i.DeletedAt,
+			&i.PinnedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsMentioning = `-- name: GetChirpsMentioning :many
+select chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.body_search, chirps.parent_id, chirps.rechirp_of_id, chirps.is_rechirp, chirps.moderation_status, chirps.publish_at, chirps.published, chirps.deleted_at, chirps.pinned_at from chirps
+join chirp_mentions on chirp_mentions.chirp_id = chirps.id
+where chirp_mentions.user_id = $1
+  and chirps.moderation_status <> 'hidden'
+  and chirps.published
+  and chirps.deleted_at is null
+  and (
+    $2::timestamp is null
+    or (chirps.created_at, chirps.id) < ($2, $3::uuid)
+  )
+order by chirps.created_at desc, chirps.id desc
+limit $4::int
+`
+
+type GetChirpsMentioningParams struct {
+	UserID         uuid.UUID
+	AfterCreatedAt sql.NullTime
+	AfterID        uuid.NullUUID
+	PageSize       int32
+}
+
+func (q *Queries) GetChirpsMentioning(ctx context.Context, arg GetChirpsMentioningParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsMentioning,
+		arg.UserID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.BodySearch,
+			&i.ParentID,
+			&i.RechirpOfID,
+			&i.IsRechirp,
+			&i.ModerationStatus,
+			&i.PublishAt,
+			&i.Published,
+			&i.DeletedAt,
+			&i.PinnedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPinnedChirps = `-- name: GetPinnedChirps :many
+select id, created_at, updated_at, body, user_id, body_search, parent_id, rechirp_of_id, is_rechirp, moderation_status, publish_at, published, deleted_at, pinned_at from chirps
+where user_id = $1 and pinned_at is not null and deleted_at is null
+order by pinned_at desc, id desc
+`
+
+func (q *Queries) GetPinnedChirps(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getPinnedChirps, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.BodySearch,
+			&i.ParentID,
+			&i.RechirpOfID,
+			&i.IsRechirp,
+			&i.ModerationStatus,
+			&i.PublishAt,
+			&i.Published,
+			&i.DeletedAt,
+			&i.PinnedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getReplies = `-- name: GetReplies :many
+select id, created_at, updated_at, body, user_id, body_search, parent_id, rechirp_of_id, is_rechirp, moderation_status, publish_at, published, deleted_at, pinned_at from chirps
+where parent_id = $1 and moderation_status <> 'hidden' and published
+  and deleted_at is null
+order by created_at, id
+`
+
+func (q *Queries) GetReplies(ctx context.Context, parentID uuid.NullUUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getReplies, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.BodySearch,
+			&i.ParentID,
+			&i.RechirpOfID,
+			&i.IsRechirp,
+			&i.ModerationStatus,
+			&i.PublishAt,
+			&i.Published,
+			&i.DeletedAt,
+			&i.PinnedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -139,3 +596,570 @@ func (q *Queries) GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Ch
 	}
 	return items, nil
 }
+
+const getScheduledChirps = `-- name: GetScheduledChirps :many
+select id, created_at, updated_at, body, user_id, body_search, parent_id, rechirp_of_id, is_rechirp, moderation_status, publish_at, published, deleted_at, pinned_at from chirps
+where user_id = $1 and not published and deleted_at is null
+order by publish_at, id
+`
+
+func (q *Queries) GetScheduledChirps(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getScheduledChirps, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.BodySearch,
+			&i.ParentID,
+			&i.RechirpOfID,
+			&i.IsRechirp,
+			&i.ModerationStatus,
+			&i.PublishAt,
+			&i.Published,
+			&i.DeletedAt,
+			&i.PinnedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getThread = `-- name: GetThread :many
+with recursive ancestors as (
+  select chirps.id, chirps.parent_id from chirps
+  where chirps.id = $1
+  union all
+  select c.id, c.parent_id from chirps c
+  join ancestors a on c.id = a.parent_id
+),
+thread as (
+  select ancestors.id from ancestors
+  where ancestors.parent_id is null
+  union all
+  select c.id from chirps c
+  join thread t on c.parent_id = t.id
+)
+select id, created_at, updated_at, body, user_id, body_search, parent_id, rechirp_of_id, is_rechirp, moderation_status, publish_at, published, deleted_at, pinned_at from chirps
+where id in (select thread.id from thread)
+order by created_at, id
+`
+
+func (q *Queries) GetThread(ctx context.Context, id uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getThread, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.BodySearch,
+			&i.ParentID,
+			&i.RechirpOfID,
+			&i.IsRechirp,
+			&i.ModerationStatus,
+			&i.PublishAt,
+			&i.Published,
+			&i.DeletedAt,
+			&i.PinnedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsAsc = `-- name: ListChirpsAsc :many
+select id, created_at, updated_at, body, user_id, body_search, parent_id, rechirp_of_id, is_rechirp, moderation_status, publish_at, published, deleted_at, pinned_at from chirps
+where ($1::uuid is null or user_id = $1)
+  and ($2::timestamp is null or created_at >= $2)
+  and ($3::timestamp is null or created_at < $3)
+  and (
+    $4::timestamp is null
+    or (created_at, id) > ($4, $5::uuid)
+  )
+  and deleted_at is null
+  and ((moderation_status <> 'hidden' and published) or user_id = $6)
+  and (not $7::bool or pinned_at is null)
+order by created_at, id
+limit $8::int
+`
+
+type ListChirpsAscParams struct {
+	AuthorID       uuid.NullUUID
+	Since          sql.NullTime
+	Until          sql.NullTime
+	AfterCreatedAt sql.NullTime
+	AfterID        uuid.NullUUID
+	ViewerID       uuid.NullUUID
+	ExcludePinned  bool
+	PageSize       int32
+}
+
+func (q *Queries) ListChirpsAsc(ctx context.Context, arg ListChirpsAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsAsc,
+		arg.AuthorID,
+		arg.Since,
+		arg.Until,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.ViewerID,
+		arg.ExcludePinned,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.BodySearch,
+			&i.ParentID,
+			&i.RechirpOfID,
+			&i.IsRechirp,
+			&i.ModerationStatus,
+			&i.PublishAt,
+			&i.Published,
+			&i.DeletedAt,
+			&i.PinnedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsDesc = `-- name: ListChirpsDesc :many
+select id, created_at, updated_at, body, user_id, body_search, parent_id, rechirp_of_id, is_rechirp, moderation_status, publish_at, published, deleted_at, pinned_at from chirps
+where ($1::uuid is null or user_id = $1)
+  and ($2::timestamp is null or created_at >= $2)
+  and ($3::timestamp is null or created_at < $3)
+  and (
+    $4::timestamp is null
+    or (created_at, id) < ($4, $5::uuid)
+  )
+  and deleted_at is null
+  and ((moderation_status <> 'hidden' and published) or user_id = $6)
+  and (not $7::bool or pinned_at is null)
+order by created_at desc, id desc
+limit $8::int
+`
+
+type ListChirpsDescParams struct {
+	AuthorID       uuid.NullUUID
+	Since          sql.NullTime
+	Until          sql.NullTime
+	AfterCreatedAt sql.NullTime
+	AfterID        uuid.NullUUID
+	ViewerID       uuid.NullUUID
+	ExcludePinned  bool
+	PageSize       int32
+}
+
+func (q *Queries) ListChirpsDesc(ctx context.Context, arg ListChirpsDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsDesc,
+		arg.AuthorID,
+		arg.Since,
+		arg.Until,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.ViewerID,
+		arg.ExcludePinned,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.BodySearch,
+			&i.ParentID,
+			&i.RechirpOfID,
+			&i.IsRechirp,
+			&i.ModerationStatus,
+			&i.PublishAt,
+			&i.Published,
+			&i.DeletedAt,
+			&i.PinnedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const pinChirp = `-- name: PinChirp :execrows
+update chirps
+set pinned_at = now()
+where id = $1 and user_id = $2 and pinned_at is null
+`
+
+type PinChirpParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) PinChirp(ctx context.Context, arg PinChirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, pinChirp, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const publishDueChirps = `-- name: PublishDueChirps :many
+update chirps
+set published = true, created_at = publish_at, updated_at = publish_at
+where not published and deleted_at is null and publish_at <= $1
+returning id, created_at, updated_at, body, user_id, body_search, parent_id, rechirp_of_id, is_rechirp, moderation_status, publish_at, published, deleted_at, pinned_at
+`
+
+func (q *Queries) PublishDueChirps(ctx context.Context, publishAt sql.NullTime) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, publishDueChirps, publishAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.BodySearch,
+			&i.ParentID,
+			&i.RechirpOfID,
+			&i.IsRechirp,
+			&i.ModerationStatus,
+			&i.PublishAt,
+			&i.Published,
+			&i.DeletedAt,
+			&i.PinnedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeDeletedChirps = `-- name: PurgeDeletedChirps :execrows
+delete from chirps
+where deleted_at < now() - make_interval(secs => $1::float8)
+`
+
+func (q *Queries) PurgeDeletedChirps(ctx context.Context, windowSeconds float64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedChirps, windowSeconds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreChirp = `-- name: RestoreChirp :one
+update chirps
+set deleted_at = null
+where id = $1 and user_id = $2 and deleted_at >= now() - make_interval(secs => $3::float8)
+returning id, created_at, updated_at, body, user_id, body_search, parent_id, rechirp_of_id, is_rechirp, moderation_status, publish_at, published, deleted_at, pinned_at
+`
+
+type RestoreChirpParams struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	WindowSeconds float64
+}
+
+func (q *Queries) RestoreChirp(ctx context.Context, arg RestoreChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, restoreChirp, arg.ID, arg.UserID, arg.WindowSeconds)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.BodySearch,
+		&i.ParentID,
+		&i.RechirpOfID,
+		&i.IsRechirp,
+		&i.ModerationStatus,
+		&i.PublishAt,
+		&i.Published,
+		&i.DeletedAt,
+		&i.PinnedAt,
+	)
+	return i, err
+}
+
+const searchChirps = `-- name: SearchChirps :many
+-- The body is HTML-escaped before it's highlighted, so the snippet is safe to
+-- render as HTML.
+select
+  matches.id, matches.created_at, matches.updated_at, matches.body, matches.user_id, matches.body_search, matches.parent_id, matches.rechirp_of_id, matches.is_rechirp, matches.moderation_status, matches.publish_at, matches.published, matches.deleted_at, matches.pinned_at, matches.rank,
+  ts_headline(
+    'english',
+    replace(replace(replace(matches.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+    to_tsquery('english', $1),
+    'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=5'
+  )::text as snippet
+from (
+  select chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.body_search, chirps.parent_id, chirps.rechirp_of_id, chirps.is_rechirp, chirps.moderation_status, chirps.publish_at, chirps.published, chirps.deleted_at, chirps.pinned_at, ts_rank(chirps.body_search, query)::real as rank
+  from chirps, to_tsquery('english', $1) query
+  where chirps.body_search @@ query
+    and chirps.moderation_status <> 'hidden'
+    and chirps.published
+    and chirps.deleted_at is null
+) matches
+where $2::real is null
+  or (matches.rank, matches.created_at, matches.id) < ($2::real, $3::timestamp, $4::uuid)
+order by matches.rank desc, matches.created_at desc, matches.id desc
+limit $5::int
+`
+
+type SearchChirpsParams struct {
+	Query          string
+	AfterRank      sql.NullFloat64
+	AfterCreatedAt sql.NullTime
+	AfterID        uuid.NullUUID
+	PageSize       int32
+}
+
+type SearchChirpsRow struct {
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Body             string
+	UserID           uuid.UUID
+	BodySearch       interface{}
+	ParentID         uuid.NullUUID
+	RechirpOfID      uuid.NullUUID
+	IsRechirp        bool
+	ModerationStatus string
+	PublishAt        sql.NullTime
+	Published        bool
+	DeletedAt        sql.NullTime
+	PinnedAt         sql.NullTime
+	Rank             float32
+	Snippet          string
+}
+
+func (q *Queries) SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]SearchChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirps,
+		arg.Query,
+		arg.AfterRank,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsRow
+	for rows.Next() {
+		var i SearchChirpsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.BodySearch,
+			&i.ParentID,
+			&i.RechirpOfID,
+			&i.IsRechirp,
+			&i.ModerationStatus,
+			&i.PublishAt,
+			&i.Published,
+			&i.DeletedAt,
+			&i.PinnedAt,
+			&i.Rank,
+			&i.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setChirpModerationStatus = `-- name: SetChirpModerationStatus :one
+update chirps
+set moderation_status = $1
+where id = $2
+returning id, created_at, updated_at, body, user_id, body_search, parent_id, rechirp_of_id, is_rechirp, moderation_status, publish_at, published, deleted_at, pinned_at
+`
+
+type SetChirpModerationStatusParams struct {
+	ModerationStatus string
+	ID               uuid.UUID
+}
+
+func (q *Queries) SetChirpModerationStatus(ctx context.Context, arg SetChirpModerationStatusParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, setChirpModerationStatus, arg.ModerationStatus, arg.ID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.BodySearch,
+		&i.ParentID,
+		&i.RechirpOfID,
+		&i.IsRechirp,
+		&i.ModerationStatus,
+		&i.PublishAt,
+		&i.Published,
+		&i.DeletedAt,
+		&i.PinnedAt,
+	)
+	return i, err
+}
+
+const softDeleteChirp = `-- name: SoftDeleteChirp :execrows
+update chirps
+set deleted_at = now(), pinned_at = null
+where id = $1 and user_id = $2 and deleted_at is null
+`
+
+type SoftDeleteChirpParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) SoftDeleteChirp(ctx context.Context, arg SoftDeleteChirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, softDeleteChirp, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const unpinChirp = `-- name: UnpinChirp :execrows
+update chirps
+set pinned_at = null
+where id = $1 and user_id = $2 and pinned_at is not null
+`
+
+type UnpinChirpParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) UnpinChirp(ctx context.Context, arg UnpinChirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, unpinChirp, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateChirp = `-- name: UpdateChirp :one
+update chirps
+set body = $1, updated_at = now()
+where id = $2 and user_id = $3
+returning id, created_at, updated_at, body, user_id, body_search, parent_id, rechirp_of_id, is_rechirp, moderation_status, publish_at, published, deleted_at, pinned_at
+`
+
+type UpdateChirpParams struct {
+	Body   string
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) UpdateChirp(ctx context.Context, arg UpdateChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirp, arg.Body, arg.ID, arg.UserID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.BodySearch,
+		&i.ParentID,
+		&i.RechirpOfID,
+		&i.IsRechirp,
+		&i.ModerationStatus,
+		&i.PublishAt,
+		&i.Published,
+		&i.DeletedAt,
+		&i.PinnedAt,
+	)
+	return i, err
+}
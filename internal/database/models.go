@@ -12,11 +12,131 @@ import (
 )
 
 type Chirp struct {
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Body             string
+	UserID           uuid.UUID
+	BodySearch       interface{}
+	ParentID         uuid.NullUUID
+	RechirpOfID      uuid.NullUUID
+	IsRechirp        bool
+	ModerationStatus string
+	PublishAt        sql.NullTime
+	Published        bool
+	DeletedAt        sql.NullTime
+	PinnedAt         sql.NullTime
+}
+
+type ChirpHashtag struct {
+	ChirpID   uuid.UUID
+	HashtagID uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpMention struct {
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+}
+
+type ChirpReport struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	ReporterID uuid.NullUUID
+	Reason     string
+	CreatedAt  time.Time
+	ResolvedAt sql.NullTime
+}
+
+type ChirpRevision struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	Body       string
+	CreatedAt  time.Time
+	ReplacedAt time.Time
+}
+
+type Draft struct {
 	ID        uuid.UUID
+	UserID    uuid.UUID
+	Body      string
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Body      string
+}
+
+type EmailVerificationToken struct {
+	TokenHash string
 	UserID    uuid.UUID
+	Email     string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type Hashtag struct {
+	ID        uuid.UUID
+	Tag       string
+	CreatedAt time.Time
+}
+
+type Like struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type LinkPreview struct {
+	ChirpID     uuid.UUID
+	Url         string
+	Status      string
+	Title       string
+	Description string
+	ImageUrl    string
+	SiteName    string
+	CreatedAt   time.Time
+	FetchedAt   sql.NullTime
+}
+
+type Medium struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	ChirpID      uuid.NullUUID
+	ContentType  string
+	SizeBytes    int64
+	StorageKey   string
+	CreatedAt    time.Time
+	Width        int32
+	Height       int32
+	ThumbnailKey sql.NullString
+}
+
+type ModerationWord struct {
+	Word      string
+	Action    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type PasswordResetToken struct {
+	TokenHash string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type RecoveryCode struct {
+	CodeHash  string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	UsedAt    sql.NullTime
 }
 
 type RefreshToken struct {
@@ -29,10 +149,25 @@ type RefreshToken struct {
 }
 
 type User struct {
-	ID             uuid.UUID
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Email           string
+	HashedPassword  string
+	IsChirpyRed     bool
+	DisplayName     string
+	Bio             string
+	AvatarUrl       string
+	Handle          sql.NullString
+	EmailVerifiedAt sql.NullTime
+}
+
+type UserTotp struct {
+	UserID         uuid.UUID
+	Secret         string
 	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    bool
+	ConfirmedAt    sql.NullTime
+	LastUsedStep   int64
+	FailedAttempts int32
+	LockedUntil    sql.NullTime
 }
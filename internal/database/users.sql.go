@@ -7,23 +7,25 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
 )
 
 const createUser = `-- name: CreateUser :one
-insert into users (id, created_at, updated_at, email, hashed_password)
-values (gen_random_uuid(), now(), now(), $1, $2)
-returning id, created_at, updated_at, email, hashed_password, is_chirpy_red
+insert into users (id, created_at, updated_at, email, hashed_password, handle)
+values (gen_random_uuid(), now(), now(), $1, $2, $3)
+returning id, created_at, updated_at, email, hashed_password, is_chirpy_red, display_name, bio, avatar_url, handle, email_verified_at
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	Handle         sql.NullString
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.Handle)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -32,12 +34,41 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.Handle,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const getUser = `-- name: GetUser :one
+select id, created_at, updated_at, email, hashed_password, is_chirpy_red, display_name, bio, avatar_url, handle, email_verified_at from users
+where id = $1
+`
+
+func (q *Queries) GetUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.Handle,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-select id, created_at, updated_at, email, hashed_password, is_chirpy_red from users
+select id, created_at, updated_at, email, hashed_password, is_chirpy_red, display_name, bio, avatar_url, handle, email_verified_at from users
 where email = $1
 `
 
@@ -51,15 +82,150 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.Handle,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const getUserByHandle = `-- name: GetUserByHandle :one
+select id, created_at, updated_at, email, hashed_password, is_chirpy_red, display_name, bio, avatar_url, handle, email_verified_at from users
+where lower(handle) = lower($1)
+`
+
+func (q *Queries) GetUserByHandle(ctx context.Context, handle string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByHandle, handle)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.Handle,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const getUserForUpdate = `-- name: GetUserForUpdate :one
+select id, created_at, updated_at, email, hashed_password, is_chirpy_red, display_name, bio, avatar_url, handle, email_verified_at from users
+where id = $1
+for update
+`
+
+func (q *Queries) GetUserForUpdate(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserForUpdate, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.Handle,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const markUserEmailVerified = `-- name: MarkUserEmailVerified :one
+update users
+set email_verified_at = now(), updated_at = now()
+where id = $1 and email = $2
+returning id, created_at, updated_at, email, hashed_password, is_chirpy_red, display_name, bio, avatar_url, handle, email_verified_at
+`
+
+type MarkUserEmailVerifiedParams struct {
+	ID    uuid.UUID
+	Email string
+}
+
+func (q *Queries) MarkUserEmailVerified(ctx context.Context, arg MarkUserEmailVerifiedParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, markUserEmailVerified, arg.ID, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.Handle,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
+const setUserHandle = `-- name: SetUserHandle :one
+update users
+set handle = $1, updated_at = now()
+where id = $2
+returning id, created_at, updated_at, email, hashed_password, is_chirpy_red, display_name, bio, avatar_url, handle, email_verified_at
+`
+
+type SetUserHandleParams struct {
+	Handle sql.NullString
+	ID     uuid.UUID
+}
+
+func (q *Queries) SetUserHandle(ctx context.Context, arg SetUserHandleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserHandle, arg.Handle, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.Handle,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const setUserPassword = `-- name: SetUserPassword :exec
+update users
+set hashed_password = $1, updated_at = now()
+where id = $2
+`
+
+type SetUserPasswordParams struct {
+	HashedPassword string
+	ID             uuid.UUID
+}
+
+func (q *Queries) SetUserPassword(ctx context.Context, arg SetUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, setUserPassword, arg.HashedPassword, arg.ID)
+	return err
+}
+
 const updateUser = `-- name: UpdateUser :one
 update users
-set email = $1, hashed_password = $2, updated_at = now()
+set email = $1,
+  hashed_password = $2,
+  email_verified_at = case when email = $1 then email_verified_at end,
+  updated_at = now()
 where id = $3
-returning id, created_at, updated_at, email, hashed_password, is_chirpy_red
+returning id, created_at, updated_at, email, hashed_password, is_chirpy_red, display_name, bio, avatar_url, handle, email_verified_at
 `
 
 type UpdateUserParams struct {
@@ -78,6 +244,61 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.Handle,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+update users
+set email = coalesce($1, email),
+  hashed_password = coalesce($2, hashed_password),
+  display_name = coalesce($3, display_name),
+  bio = coalesce($4, bio),
+  avatar_url = coalesce($5, avatar_url),
+  email_verified_at = case
+    when coalesce($1, email) = email then email_verified_at
+  end,
+  updated_at = now()
+where id = $6
+returning id, created_at, updated_at, email, hashed_password, is_chirpy_red, display_name, bio, avatar_url, handle, email_verified_at
+`
+
+type UpdateUserProfileParams struct {
+	Email          sql.NullString
+	HashedPassword sql.NullString
+	DisplayName    sql.NullString
+	Bio            sql.NullString
+	AvatarUrl      sql.NullString
+	ID             uuid.UUID
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile,
+		arg.Email,
+		arg.HashedPassword,
+		arg.DisplayName,
+		arg.Bio,
+		arg.AvatarUrl,
+		arg.ID,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.Handle,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
@@ -86,7 +307,7 @@ const upgradeUserToRed = `-- name: UpgradeUserToRed :one
 update users
 set is_chirpy_red = true
 where id = $1
-returning id, created_at, updated_at, email, hashed_password, is_chirpy_red
+returning id, created_at, updated_at, email, hashed_password, is_chirpy_red, display_name, bio, avatar_url, handle, email_verified_at
 `
 
 func (q *Queries) UpgradeUserToRed(ctx context.Context, id uuid.UUID) (User, error) {
@@ -99,6 +320,11 @@ func (q *Queries) UpgradeUserToRed(ctx context.Context, id uuid.UUID) (User, err
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.Handle,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
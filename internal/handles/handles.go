@@ -0,0 +1,79 @@
+package handles
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	MinLength = 3
+	MaxLength = 20
+)
+
+var (
+	ErrInvalid  = errors.New("invalid handle")
+	ErrReserved = errors.New("handle is reserved")
+)
+
+// reserved holds handles users can't take, either because they name a part
+// of the API or because they could be mistaken for staff. Entries are lower
+// case; comparisons ignore case.
+var reserved = map[string]bool{
+	"admin":         true,
+	"administrator": true,
+	"api":           true,
+	"app":           true,
+	"chirps":        true,
+	"chirpy":        true,
+	"everyone":      true,
+	"followers":     true,
+	"following":     true,
+	"help":          true,
+	"here":          true,
+	"login":         true,
+	"logout":        true,
+	"mentions":      true,
+	"moderator":     true,
+	"null":          true,
+	"root":          true,
+	"settings":      true,
+	"signup":        true,
+	"staff":         true,
+	"support":       true,
+	"system":        true,
+	"undefined":     true,
+}
+
+// Validate checks that h can be used as a handle: MinLength to MaxLength ASCII
+// letters, digits and underscores, starting with a letter, and not reserved.
+// A leading @ is not part of the handle.
+func Validate(h string) error {
+	if len(h) < MinLength || len(h) > MaxLength {
+		return fmt.Errorf("%w: must be %d to %d characters long", ErrInvalid, MinLength, MaxLength)
+	}
+
+	if !isLetter(h[0]) {
+		return fmt.Errorf("%w: must start with a letter", ErrInvalid)
+	}
+
+	for i := 0; i < len(h); i++ {
+		if c := h[i]; !isLetter(c) && !isDigit(c) && c != '_' {
+			return fmt.Errorf("%w: may only contain letters, digits and underscores", ErrInvalid)
+		}
+	}
+
+	if reserved[strings.ToLower(h)] {
+		return fmt.Errorf("%w: %q", ErrReserved, h)
+	}
+
+	return nil
+}
+
+func isLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,35 @@
+package handles
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		handle string
+		want   error
+	}{
+		{"alice", nil},
+		{"Bob_99", nil},
+		{"abc", nil},
+		{"a2345678901234567890", nil},
+		{"ab", ErrInvalid},
+		{"a23456789012345678901", ErrInvalid},
+		{"9lives", ErrInvalid},
+		{"_alice", ErrInvalid},
+		{"@alice", ErrInvalid},
+		{"al ice", ErrInvalid},
+		{"al-ice", ErrInvalid},
+		{"álice", ErrInvalid},
+		{"admin", ErrReserved},
+		{"ADMIN", ErrReserved},
+		{"Me_", nil},
+		{"chirps", ErrReserved},
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.handle); !errors.Is(err, tt.want) {
+			t.Errorf("Validate(%q) error = %v, want %v", tt.handle, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var errGIFTruncated = errors.New("gif is truncated")
+
+// gifFrameStats walks the blocks of a GIF without decoding any image data
+// and returns the number of frames and their summed area. gif.DecodeAll
+// allocates every frame up front, so these have to be checked first.
+func gifFrameStats(data []byte) (frames, pixels int, err error) {
+	// Header and logical screen descriptor.
+	if len(data) < 13 {
+		return 0, 0, errGIFTruncated
+	}
+	i := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		i += 3 << (flags&0x07 + 1)
+	}
+
+	for {
+		if i >= len(data) {
+			return 0, 0, errGIFTruncated
+		}
+
+		switch data[i] {
+		case 0x21: // Extension: label, then data sub-blocks.
+			if i, err = skipSubBlocks(data, i+2); err != nil {
+				return 0, 0, err
+			}
+		case 0x2C: // Image descriptor.
+			if i+10 > len(data) {
+				return 0, 0, errGIFTruncated
+			}
+			width := int(binary.LittleEndian.Uint16(data[i+5:]))
+			height := int(binary.LittleEndian.Uint16(data[i+7:]))
+			frames++
+			pixels += width * height
+
+			flags := data[i+9]
+			i += 10
+			if flags&0x80 != 0 {
+				i += 3 << (flags&0x07 + 1)
+			}
+			// LZW minimum code size, then the image data sub-blocks.
+			if i, err = skipSubBlocks(data, i+1); err != nil {
+				return 0, 0, err
+			}
+		case 0x3B: // Trailer.
+			return frames, pixels, nil
+		default:
+			return 0, 0, errors.New("gif has an unknown block")
+		}
+	}
+}
+
+// skipSubBlocks returns the index just past the sub-block sequence that
+// starts at data[i] and ends with an empty block.
+func skipSubBlocks(data []byte, i int) (int, error) {
+	for {
+		if i >= len(data) {
+			return 0, errGIFTruncated
+		}
+		size := int(data[i])
+		i += 1 + size
+		if size == 0 {
+			return i, nil
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+const jpegQuality = 85
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrTooLarge          = errors.New("image is too large")
+)
+
+// Options limit the images Process accepts and the sizes it produces.
+type Options struct {
+	// MaxPixels rejects images with more pixels than this before they are
+	// decoded, so a small file can't expand into a huge bitmap. The pixels
+	// of every frame of an animation count.
+	MaxPixels int
+	// MaxFrames is the most frames an animated GIF may have.
+	MaxFrames int
+	// MaxDimension is the longest side an original is stored with. Larger
+	// images are scaled down.
+	MaxDimension int
+	// ThumbnailSize is the longest side of the thumbnail.
+	ThumbnailSize int
+}
+
+// Image is an encoded image ready to be stored.
+type Image struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// Result holds the normalized original of an upload and its thumbnail.
+type Result struct {
+	Original  Image
+	Thumbnail Image
+}
+
+// Process decodes a PNG, JPEG or GIF, applies its EXIF orientation and
+// re-encodes it. Re-encoding drops every metadata segment, including EXIF
+// data such as GPS coordinates. Originals keep their format; thumbnails of
+// JPEGs are JPEGs and thumbnails of everything else are PNGs so
+// transparency survives.
+func Process(data []byte, opts Options) (Result, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+	if cfg.Width*cfg.Height > opts.MaxPixels {
+		return Result{}, fmt.Errorf("%w: %dx%d", ErrTooLarge, cfg.Width, cfg.Height)
+	}
+
+	switch format {
+	case "jpeg":
+		return processJPEG(data, opts)
+	case "png":
+		return processPNG(data, opts)
+	case "gif":
+		return processGIF(data, opts)
+	}
+	return Result{}, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+}
+
+func processJPEG(data []byte, opts Options) (Result, error) {
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Result{}, fmt.Errorf("can't decode jpeg: %w", err)
+	}
+	img = orient(img, jpegOrientation(data))
+
+	original, err := encodeJPEG(fit(img, opts.MaxDimension))
+	if err != nil {
+		return Result{}, err
+	}
+	thumbnail, err := encodeJPEG(fit(img, opts.ThumbnailSize))
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{Original: original, Thumbnail: thumbnail}, nil
+}
+
+func processPNG(data []byte, opts Options) (Result, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Result{}, fmt.Errorf("can't decode png: %w", err)
+	}
+
+	original, err := encodePNG(fit(img, opts.MaxDimension))
+	if err != nil {
+		return Result{}, err
+	}
+	thumbnail, err := encodePNG(fit(img, opts.ThumbnailSize))
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{Original: original, Thumbnail: thumbnail}, nil
+}
+
+// processGIF keeps animations intact. Scaling the frames of an animation
+// would mean compositing them first, so animations larger than MaxDimension
+// are rejected instead; the thumbnail shows the first frame.
+func processGIF(data []byte, opts Options) (Result, error) {
+	frames, pixels, err := gifFrameStats(data)
+	if err != nil {
+		return Result{}, fmt.Errorf("can't decode gif: %w", err)
+	}
+	if frames > opts.MaxFrames {
+		return Result{}, fmt.Errorf("%w: gif has %d frames", ErrTooLarge, frames)
+	}
+	if pixels > opts.MaxPixels {
+		return Result{}, fmt.Errorf("%w: gif frames have %d pixels", ErrTooLarge, pixels)
+	}
+
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return Result{}, fmt.Errorf("can't decode gif: %w", err)
+	}
+	if len(g.Image) == 0 {
+		return Result{}, fmt.Errorf("%w: gif has no frames", ErrUnsupportedFormat)
+	}
+
+	first := g.Image[0]
+	width, height := g.Config.Width, g.Config.Height
+
+	if len(g.Image) > 1 && max(width, height) > opts.MaxDimension {
+		return Result{}, fmt.Errorf("%w: animated gif is %dx%d", ErrTooLarge, width, height)
+	}
+
+	if len(g.Image) == 1 && max(width, height) > opts.MaxDimension {
+		scaled := fit(first, opts.MaxDimension)
+		paletted := image.NewPaletted(scaled.Bounds(), first.Palette)
+		draw.FloydSteinberg.Draw(paletted, paletted.Bounds(), scaled, scaled.Bounds().Min)
+		g.Image[0] = paletted
+		g.Config.Width, g.Config.Height = paletted.Bounds().Dx(), paletted.Bounds().Dy()
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		return Result{}, fmt.Errorf("can't encode gif: %w", err)
+	}
+	original := Image{
+		Data:        buf.Bytes(),
+		ContentType: "image/gif",
+		Width:       g.Config.Width,
+		Height:      g.Config.Height,
+	}
+
+	thumbnail, err := encodePNG(fit(first, opts.ThumbnailSize))
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{Original: original, Thumbnail: thumbnail}, nil
+}
+
+func encodeJPEG(img image.Image) (Image, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return Image{}, fmt.Errorf("can't encode jpeg: %w", err)
+	}
+	return newImage(buf.Bytes(), "image/jpeg", img), nil
+}
+
+func encodePNG(img image.Image) (Image, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return Image{}, fmt.Errorf("can't encode png: %w", err)
+	}
+	return newImage(buf.Bytes(), "image/png", img), nil
+}
+
+func newImage(data []byte, contentType string, img image.Image) Image {
+	return Image{
+		Data:        data,
+		ContentType: contentType,
+		Width:       img.Bounds().Dx(),
+		Height:      img.Bounds().Dy(),
+	}
+}
@@ -0,0 +1,223 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+var testOptions = Options{
+	MaxPixels:     1000 * 1000,
+	MaxFrames:     10,
+	MaxDimension:  200,
+	ThumbnailSize: 50,
+}
+
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+// jpegWithExif encodes img as a JPEG with an EXIF segment that sets the
+// orientation and carries a marker string standing in for private metadata.
+func jpegWithExif(t *testing.T, img image.Image, orientation uint16) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("jpeg.Encode() error = %v", err)
+	}
+	encoded := buf.Bytes()
+
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, exifOrientationTag)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	tiff = append(tiff, "secret-location"...)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	out := append([]byte{}, encoded[:2]...)
+	out = append(out, app1...)
+	return append(out, encoded[2:]...)
+}
+
+func TestProcessJPEGStripsExifAndOrients(t *testing.T) {
+	data := jpegWithExif(t, testImage(40, 20), 6)
+	if got := jpegOrientation(data); got != 6 {
+		t.Fatalf("jpegOrientation() = %d, want 6", got)
+	}
+
+	res, err := Process(data, testOptions)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if bytes.Contains(res.Original.Data, []byte("secret-location")) || bytes.Contains(res.Original.Data, []byte("Exif")) {
+		t.Error("Process() kept EXIF data in the original")
+	}
+	if res.Original.ContentType != "image/jpeg" {
+		t.Errorf("Original.ContentType = %q, want image/jpeg", res.Original.ContentType)
+	}
+	if res.Original.Width != 20 || res.Original.Height != 40 {
+		t.Errorf("Original size = %dx%d, want 20x40", res.Original.Width, res.Original.Height)
+	}
+}
+
+func TestProcessScalesDown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(400, 100)); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+
+	res, err := Process(buf.Bytes(), testOptions)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		img           Image
+		width, height int
+	}{
+		{"original", res.Original, 200, 50},
+		{"thumbnail", res.Thumbnail, 50, 12},
+	}
+	for _, tt := range tests {
+		if tt.img.ContentType != "image/png" {
+			t.Errorf("%s content type = %q, want image/png", tt.name, tt.img.ContentType)
+		}
+		if tt.img.Width != tt.width || tt.img.Height != tt.height {
+			t.Errorf("%s size = %dx%d, want %dx%d", tt.name, tt.img.Width, tt.img.Height, tt.width, tt.height)
+		}
+		cfg, err := png.DecodeConfig(bytes.NewReader(tt.img.Data))
+		if err != nil {
+			t.Fatalf("%s: png.DecodeConfig() error = %v", tt.name, err)
+		}
+		if cfg.Width != tt.width || cfg.Height != tt.height {
+			t.Errorf("%s encoded size = %dx%d, want %dx%d", tt.name, cfg.Width, cfg.Height, tt.width, tt.height)
+		}
+	}
+}
+
+// animatedGIF encodes an animation with the given number of size×size
+// frames.
+func animatedGIF(t *testing.T, frames, size int) []byte {
+	t.Helper()
+
+	palette := color.Palette{color.Black, color.White}
+	g := &gif.GIF{}
+	for i := 0; i < frames; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, size, size), palette)
+		frame.SetColorIndex(i%size, i%size, 1)
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatalf("gif.EncodeAll() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessKeepsAnimatedGIF(t *testing.T) {
+	res, err := Process(animatedGIF(t, 3, 100), testOptions)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	out, err := gif.DecodeAll(bytes.NewReader(res.Original.Data))
+	if err != nil {
+		t.Fatalf("gif.DecodeAll() error = %v", err)
+	}
+	if len(out.Image) != 3 {
+		t.Errorf("Original has %d frames, want 3", len(out.Image))
+	}
+	if res.Thumbnail.ContentType != "image/png" || res.Thumbnail.Width != 50 {
+		t.Errorf("Thumbnail = %s %dx%d, want image/png 50x50", res.Thumbnail.ContentType, res.Thumbnail.Width, res.Thumbnail.Height)
+	}
+}
+
+func TestProcessRejects(t *testing.T) {
+	var big bytes.Buffer
+	if err := png.Encode(&big, image.NewGray(image.Rect(0, 0, 2000, 1000))); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"text", []byte("definitely not an image"), ErrUnsupportedFormat},
+		{"webp", []byte("RIFF\x24\x00\x00\x00WEBPVP8 "), ErrUnsupportedFormat},
+		{"too many pixels", big.Bytes(), ErrTooLarge},
+		{"too many frames", animatedGIF(t, 11, 10), ErrTooLarge},
+		{"too many pixels in all frames", animatedGIF(t, 5, 500), ErrTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Process(tt.data, testOptions); !errors.Is(err, tt.want) {
+				t.Errorf("Process() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrient(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	left := color.RGBA{R: 255, A: 255}
+	right := color.RGBA{B: 255, A: 255}
+	img.Set(0, 0, left)
+	img.Set(1, 0, right)
+
+	tests := []struct {
+		orientation int
+		x, y        int
+		want        color.RGBA
+	}{
+		{2, 0, 0, right},
+		{3, 0, 0, right},
+		{6, 0, 0, left},
+		{6, 0, 1, right},
+		{8, 0, 0, right},
+		{8, 0, 1, left},
+	}
+	for _, tt := range tests {
+		got := color.RGBAModel.Convert(orient(img, tt.orientation).At(tt.x, tt.y))
+		if got != tt.want {
+			t.Errorf("orient(%d).At(%d, %d) = %v, want %v", tt.orientation, tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestGIFFrameStats(t *testing.T) {
+	frames, pixels, err := gifFrameStats(animatedGIF(t, 4, 30))
+	if err != nil {
+		t.Fatalf("gifFrameStats() error = %v", err)
+	}
+	if frames != 4 || pixels != 4*30*30 {
+		t.Errorf("gifFrameStats() = %d frames, %d pixels, want 4, %d", frames, pixels, 4*30*30)
+	}
+
+	if _, _, err := gifFrameStats(animatedGIF(t, 3, 100)[:200]); err == nil {
+		t.Error("gifFrameStats() accepted a truncated gif")
+	}
+}
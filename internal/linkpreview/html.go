@@ -0,0 +1,157 @@
+package linkpreview
+
+import (
+	"html"
+	"strings"
+)
+
+// parseHTML reads the title and OpenGraph metadata from the head of a page.
+// It's a small scanner rather than a full HTML parser: it only looks at
+// <title> and <meta> and stops at the start of the body.
+func parseHTML(doc string) Preview {
+	lower := asciiLower(doc)
+	meta := map[string]string{}
+	title := ""
+
+	for i := 0; i < len(doc); {
+		start := strings.IndexByte(doc[i:], '<')
+		if start < 0 {
+			break
+		}
+		i += start
+
+		if strings.HasPrefix(doc[i:], "<!--") {
+			end := strings.Index(doc[i:], "-->")
+			if end < 0 {
+				break
+			}
+			i += end + 3
+			continue
+		}
+
+		name, attrs, end := parseTag(doc, lower, i)
+		i = end
+
+		switch name {
+		case "title":
+			closing := strings.Index(lower[i:], "</title")
+			if closing < 0 {
+				break
+			}
+			if title == "" {
+				title = html.UnescapeString(doc[i : i+closing])
+			}
+			i += closing
+		case "meta":
+			key := attrs["property"]
+			if key == "" {
+				key = attrs["name"]
+			}
+			key = asciiLower(key)
+			if _, ok := meta[key]; key != "" && !ok {
+				meta[key] = html.UnescapeString(attrs["content"])
+			}
+		case "script", "style":
+			closing := strings.Index(lower[i:], "</"+name)
+			if closing < 0 {
+				i = len(doc)
+				break
+			}
+			i += closing
+		case "body", "/head":
+			i = len(doc)
+		}
+	}
+
+	return Preview{
+		Title:       cleanText(firstNonEmpty(meta["og:title"], meta["twitter:title"], title)),
+		Description: cleanText(firstNonEmpty(meta["og:description"], meta["twitter:description"], meta["description"])),
+		ImageURL:    strings.TrimSpace(firstNonEmpty(meta["og:image"], meta["twitter:image"])),
+		SiteName:    cleanText(meta["og:site_name"]),
+	}
+}
+
+// parseTag reads the tag that starts at doc[i], which must be a '<'. It
+// returns the lowercased tag name, its attributes with lowercased names and
+// the index just past the closing '>'.
+func parseTag(doc, lower string, i int) (string, map[string]string, int) {
+	i++
+	nameStart := i
+	for i < len(doc) && !isSpace(doc[i]) && doc[i] != '>' && (doc[i] != '/' || i == nameStart) {
+		i++
+	}
+	name := lower[nameStart:i]
+
+	attrs := map[string]string{}
+	for i < len(doc) {
+		for i < len(doc) && (isSpace(doc[i]) || doc[i] == '/') {
+			i++
+		}
+		if i >= len(doc) || doc[i] == '>' {
+			return name, attrs, min(i+1, len(doc))
+		}
+
+		attrStart := i
+		for i < len(doc) && !isSpace(doc[i]) && doc[i] != '=' && doc[i] != '>' && doc[i] != '/' {
+			i++
+		}
+		attr := lower[attrStart:i]
+
+		for i < len(doc) && isSpace(doc[i]) {
+			i++
+		}
+		if i >= len(doc) || doc[i] != '=' {
+			attrs[attr] = ""
+			continue
+		}
+		i++
+		for i < len(doc) && isSpace(doc[i]) {
+			i++
+		}
+
+		var value string
+		if i < len(doc) && (doc[i] == '"' || doc[i] == '\'') {
+			quote := doc[i]
+			end := strings.IndexByte(doc[i+1:], quote)
+			if end < 0 {
+				return name, attrs, len(doc)
+			}
+			value = doc[i+1 : i+1+end]
+			i += end + 2
+		} else {
+			valueStart := i
+			for i < len(doc) && !isSpace(doc[i]) && doc[i] != '>' {
+				i++
+			}
+			value = doc[valueStart:i]
+		}
+		attrs[attr] = value
+	}
+
+	return name, attrs, len(doc)
+}
+
+// asciiLower lowercases ASCII letters only, so indexes into the result are
+// valid indexes into s.
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
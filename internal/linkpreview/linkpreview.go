@@ -0,0 +1,213 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxRedirects   = 5
+	maxFieldLength = 300
+	userAgent      = "ChirpyBot/1.0 (+link previews)"
+)
+
+var (
+	ErrUnsupportedURL     = errors.New("unsupported url")
+	ErrBlockedAddress     = errors.New("address is not publicly routable")
+	ErrUnsupportedContent = errors.New("response is not an html page")
+)
+
+// Preview is the metadata shown for a link.
+type Preview struct {
+	URL         string
+	Title       string
+	Description string
+	ImageURL    string
+	SiteName    string
+}
+
+// Fetcher loads the preview of a URL.
+type Fetcher interface {
+	Fetch(ctx context.Context, rawURL string) (Preview, error)
+}
+
+// HTTPFetcher fetches pages over HTTP and reads their OpenGraph metadata,
+// falling back to the <title> element.
+type HTTPFetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+// NewHTTPFetcher returns a fetcher that gives up after timeout and reads at
+// most maxBytes of every page. It refuses to connect to loopback, private and
+// link-local addresses. The check runs on the address actually dialled, so
+// neither redirects nor DNS records pointing at internal hosts get around it.
+func NewHTTPFetcher(timeout time.Duration, maxBytes int64) *HTTPFetcher {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: guardDial,
+	}
+
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	return &HTTPFetcher{
+		client: &http.Client{
+			Transport:     transport,
+			Timeout:       timeout,
+			CheckRedirect: checkRedirect,
+		},
+		maxBytes: maxBytes,
+	}
+}
+
+func (f *HTTPFetcher) Fetch(ctx context.Context, rawURL string) (Preview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Preview{}, fmt.Errorf("%w: %q", ErrUnsupportedURL, rawURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Preview{}, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return Preview{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Preview{}, fmt.Errorf("unexpected status fetching %q: %s", rawURL, resp.Status)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Preview{}, fmt.Errorf("%w: %q", ErrUnsupportedContent, mediaType)
+	}
+
+	// Metadata lives in the head, so a truncated page is still useful.
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes))
+	if err != nil {
+		return Preview{}, fmt.Errorf("can't read %q: %w", rawURL, err)
+	}
+
+	preview := parseHTML(string(body))
+	preview.URL = rawURL
+	preview.ImageURL = resolveImageURL(resp.Request.URL, preview.ImageURL)
+
+	if preview.Title == "" && preview.Description == "" {
+		return Preview{}, fmt.Errorf("no metadata found at %q", rawURL)
+	}
+
+	return preview, nil
+}
+
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("%w: redirect to %q", ErrUnsupportedURL, req.URL)
+	}
+	return nil
+}
+
+// guardDial runs after name resolution, right before a connection is made.
+func guardDial(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrBlockedAddress, host)
+	}
+
+	if !isPublic(addr) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, addr)
+	}
+
+	return nil
+}
+
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// isPublic reports whether addr is routable on the public internet.
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// resolveImageURL makes a relative og:image absolute. Images that aren't
+// served over HTTP are dropped.
+func resolveImageURL(base *url.URL, image string) string {
+	if image == "" {
+		return ""
+	}
+
+	ref, err := url.Parse(image)
+	if err != nil {
+		return ""
+	}
+
+	abs := base.ResolveReference(ref)
+	if abs.Scheme != "http" && abs.Scheme != "https" {
+		return ""
+	}
+
+	return abs.String()
+}
+
+// cleanText makes page text safe to store: invalid UTF-8 is replaced, NUL
+// bytes, which Postgres rejects in text columns, are dropped and the result
+// is shortened to maxFieldLength characters.
+func cleanText(s string) string {
+	s = strings.ReplaceAll(strings.ToValidUTF8(s, "\uFFFD"), "\x00", "")
+	s = strings.TrimSpace(s)
+	if utf8.RuneCountInString(s) <= maxFieldLength {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:maxFieldLength-1])) + "…"
+}
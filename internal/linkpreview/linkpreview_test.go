@@ -0,0 +1,176 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testFetcher returns a fetcher without the address guard, so it can talk to
+// httptest servers on the loopback interface.
+func testFetcher(srv *httptest.Server, maxBytes int64) *HTTPFetcher {
+	client := srv.Client()
+	client.Timeout = time.Second
+	client.CheckRedirect = checkRedirect
+	return &HTTPFetcher{client: client, maxBytes: maxBytes}
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/article":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(`<!doctype html><html><head>
+				<!-- <meta property="og:title" content="commented out"> -->
+				<title>Fallback &amp; title</title>
+				<meta property="og:title" content="Chirpy &amp; friends">
+				<META NAME="description" CONTENT='A page about birds'>
+				<meta property="og:image" content="/images/bird.png" />
+				<meta property="og:site_name" content=Chirpy>
+				</head><body><meta property="og:description" content="in the body"></body></html>`))
+		case "/plain":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>Just a title</title></head></html>`))
+		case "/redirect":
+			http.Redirect(w, r, "/plain", http.StatusFound)
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG"))
+		case "/huge":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><head>" + strings.Repeat(" ", 4096) + "<title>Too far</title></head></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	f := testFetcher(srv, 1024)
+	ctx := context.Background()
+
+	got, err := f.Fetch(ctx, srv.URL+"/article")
+	if err != nil {
+		t.Fatalf("Fetch(article) error = %v", err)
+	}
+	want := Preview{
+		URL:         srv.URL + "/article",
+		Title:       "Chirpy & friends",
+		Description: "A page about birds",
+		ImageURL:    srv.URL + "/images/bird.png",
+		SiteName:    "Chirpy",
+	}
+	if got != want {
+		t.Errorf("Fetch(article) = %+v, want %+v", got, want)
+	}
+
+	got, err = f.Fetch(ctx, srv.URL+"/redirect")
+	if err != nil {
+		t.Fatalf("Fetch(redirect) error = %v", err)
+	}
+	if got.Title != "Just a title" || got.URL != srv.URL+"/redirect" {
+		t.Errorf("Fetch(redirect) = %+v, want title %q", got, "Just a title")
+	}
+
+	if _, err := f.Fetch(ctx, srv.URL+"/image"); !errors.Is(err, ErrUnsupportedContent) {
+		t.Errorf("Fetch(image) error = %v, want %v", err, ErrUnsupportedContent)
+	}
+	if _, err := f.Fetch(ctx, srv.URL+"/huge"); err == nil {
+		t.Error("Fetch(huge) read past the size limit")
+	}
+	if _, err := f.Fetch(ctx, srv.URL+"/missing"); err == nil {
+		t.Error("Fetch(missing) error = nil, want an error")
+	}
+	if _, err := f.Fetch(ctx, "ftp://example.com/file"); !errors.Is(err, ErrUnsupportedURL) {
+		t.Errorf("Fetch(ftp) error = %v, want %v", err, ErrUnsupportedURL)
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	f := testFetcher(srv, 1024)
+	f.client.Timeout = 50 * time.Millisecond
+
+	if _, err := f.Fetch(context.Background(), srv.URL); err == nil {
+		t.Error("Fetch() error = nil, want a timeout")
+	}
+}
+
+func TestFetchBlocksPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached the loopback server")
+	}))
+	defer srv.Close()
+
+	f := NewHTTPFetcher(time.Second, 1024)
+	if _, err := f.Fetch(context.Background(), srv.URL); !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("Fetch() error = %v, want %v", err, ErrBlockedAddress)
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"::ffff:127.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := isPublic(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("isPublic(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestExtractURLs(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"no links here", []string{}},
+		{"read https://example.com/a, then http://example.org.", []string{"https://example.com/a", "http://example.org"}},
+		{"(see https://en.wikipedia.org/wiki/Bird_(disambiguation))", []string{"https://en.wikipedia.org/wiki/Bird_(disambiguation)"}},
+		{"twice https://example.com https://example.com", []string{"https://example.com"}},
+		{"not a link: https:// or ftp://example.com", []string{}},
+	}
+	for _, tt := range tests {
+		if got := ExtractURLs(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ExtractURLs(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseHTMLCleansText(t *testing.T) {
+	got := parseHTML("<html><head><title>Caf\xe9\x00 menu</title>" +
+		`<meta property="og:site_name" content="Bistro&#0;">` +
+		"</head></html>")
+
+	want := Preview{Title: "Caf� menu", SiteName: "Bistro�"}
+	if got != want {
+		t.Errorf("parseHTML() = %+v, want %+v", got, want)
+	}
+}
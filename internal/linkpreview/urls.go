@@ -0,0 +1,46 @@
+package linkpreview
+
+import (
+	"net/url"
+	"strings"
+)
+
+// ExtractURLs returns the distinct http and https URLs in text, in order of
+// appearance. Punctuation that ends a sentence isn't part of the URL.
+func ExtractURLs(text string) []string {
+	found := []string{}
+	seen := map[string]bool{}
+
+	for _, word := range strings.Fields(text) {
+		start := strings.Index(word, "http://")
+		if i := strings.Index(word, "https://"); i >= 0 && (start < 0 || i < start) {
+			start = i
+		}
+		if start < 0 {
+			continue
+		}
+
+		candidate := strings.TrimRight(word[start:], ".,;:!?'\"")
+		candidate = trimUnbalanced(candidate, '(', ')')
+
+		u, err := url.Parse(candidate)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		if !seen[candidate] {
+			seen[candidate] = true
+			found = append(found, candidate)
+		}
+	}
+
+	return found
+}
+
+// trimUnbalanced drops a trailing close bracket that has no matching open
+// bracket in s, as in "(see https://example.com)".
+func trimUnbalanced(s string, open, close byte) string {
+	for strings.HasSuffix(s, string(close)) && strings.Count(s, string(close)) > strings.Count(s, string(open)) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
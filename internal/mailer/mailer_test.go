@@ -0,0 +1,101 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	date := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	got, err := format("chirpy@example.com", Message{
+		To:      "user@example.com",
+		Subject: "Verify your email",
+		Body:    "Hello\nClick the link.",
+	}, date)
+	if err != nil {
+		t.Fatalf("format() error = %v", err)
+	}
+
+	want := "From: chirpy@example.com\r\n" +
+		"To: user@example.com\r\n" +
+		"Subject: Verify your email\r\n" +
+		"Date: Thu, 01 May 2025 12:00:00 +0000\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"Content-Transfer-Encoding: 8bit\r\n" +
+		"\r\n" +
+		"Hello\r\nClick the link."
+	if string(got) != want {
+		t.Errorf("format() = %q, want %q", got, want)
+	}
+}
+
+func TestFormatEncodesSubject(t *testing.T) {
+	got, err := format("a@example.com", Message{To: "b@example.com", Subject: "Grüße"}, time.Now())
+	if err != nil {
+		t.Fatalf("format() error = %v", err)
+	}
+	if !strings.Contains(string(got), "Subject: =?utf-8?q?Gr=C3=BC=C3=9Fe?=\r\n") {
+		t.Errorf("format() = %q, want encoded subject", got)
+	}
+}
+
+func TestFormatRejectsHeaderInjection(t *testing.T) {
+	tests := []Message{
+		{To: "user@example.com\r\nBcc: victim@example.com", Subject: "Hi"},
+		{To: "user@example.com", Subject: "Hi\nBcc: victim@example.com"},
+	}
+	for _, msg := range tests {
+		if _, err := format("a@example.com", msg, time.Now()); !errors.Is(err, ErrInvalidHeader) {
+			t.Errorf("format(%q) error = %v, want ErrInvalidHeader", msg, err)
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	m := &Writer{W: &buf, From: "chirpy@example.com"}
+
+	if err := m.Send(context.Background(), Message{To: "user@example.com", Subject: "Hi", Body: "token"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "To: user@example.com\r\n") || !strings.Contains(buf.String(), "\r\n\r\ntoken") {
+		t.Errorf("Send() wrote %q", buf.String())
+	}
+}
+
+func TestDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m, err := NewDir(dir, "chirpy@example.com")
+	if err != nil {
+		t.Fatalf("NewDir() error = %v", err)
+	}
+
+	for _, to := range []string{"a@example.com", "b@example.com"} {
+		if err := m.Send(context.Background(), Message{To: to, Subject: "Hi", Body: "token"}); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil {
+		t.Fatalf("Glob() error = %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("got %d files, want 2", len(files))
+	}
+
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !strings.Contains(string(data), "To: a@example.com\r\n") {
+		t.Errorf("first file = %q, want the first message", data)
+	}
+}
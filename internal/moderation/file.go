@@ -0,0 +1,60 @@
+package moderation
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// FileSource reads rules from a text file with one word per line, optionally
+// followed by an action. Words without an action are masked. Blank lines and
+// lines starting with # are ignored.
+//
+//	kerfuffle
+//	sharbert reject
+type FileSource struct {
+	Path string
+}
+
+func (f FileSource) Rules(ctx context.Context) ([]Rule, error) {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	rules := []Rule{}
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		rule := Rule{Word: fields[0], Action: ActionMask}
+		switch len(fields) {
+		case 1:
+		case 2:
+			action, err := ParseAction(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", f.Path, lineNo, err)
+			}
+			rule.Action = action
+		default:
+			return nil, fmt.Errorf("%s:%d: expected a word and an optional action", f.Path, lineNo)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,224 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+type Action string
+
+const (
+	ActionMask   Action = "mask"
+	ActionFlag   Action = "flag"
+	ActionReject Action = "reject"
+)
+
+const mask = "****"
+
+var (
+	ErrTooLong  = errors.New("chirp is too long")
+	ErrRejected = errors.New("chirp contains prohibited words")
+)
+
+// Rule applies Action whenever Word appears in a text.
+type Rule struct {
+	Word   string
+	Action Action
+}
+
+// Source supplies moderation rules, e.g. from a file or a database table.
+type Source interface {
+	Rules(ctx context.Context) ([]Rule, error)
+}
+
+// Result describes a text that passed moderation.
+type Result struct {
+	// Text is the input with every masked word replaced.
+	Text string
+	// Flagged is set when the text matched a flag rule and should be
+	// reviewed by a human.
+	Flagged bool
+	// Matches lists the normalized words that matched a rule.
+	Matches []string
+}
+
+// Reason describes for moderators why the text was flagged.
+func (r Result) Reason() string {
+	return "automatically flagged, matched: " + strings.Join(r.Matches, ", ")
+}
+
+// Engine checks texts against the rules of its sources. Rules are cached in
+// memory; call Reload after a source changes.
+type Engine struct {
+	maxRunes int
+	sources  []Source
+
+	mu    sync.RWMutex
+	rules map[string]Action
+}
+
+func NewEngine(maxRunes int, sources ...Source) *Engine {
+	return &Engine{
+		maxRunes: maxRunes,
+		sources:  sources,
+		rules:    map[string]Action{},
+	}
+}
+
+func ParseAction(s string) (Action, error) {
+	switch a := Action(strings.ToLower(s)); a {
+	case ActionMask, ActionFlag, ActionReject:
+		return a, nil
+	}
+	return "", fmt.Errorf("unknown moderation action: %q", s)
+}
+
+// Reload replaces the cached rules with the current rules of every source.
+// When a word appears more than once the strictest action wins.
+func (e *Engine) Reload(ctx context.Context) error {
+	rules := map[string]Action{}
+	for _, src := range e.sources {
+		srcRules, err := src.Rules(ctx)
+		if err != nil {
+			return fmt.Errorf("can't load moderation rules: %w", err)
+		}
+		for _, rule := range srcRules {
+			word := Normalize(rule.Word)
+			if word == "" {
+				continue
+			}
+			if severity(rule.Action) > severity(rules[word]) {
+				rules[word] = rule.Action
+			}
+		}
+	}
+
+	e.mu.Lock()
+	e.rules = rules
+	e.mu.Unlock()
+
+	return nil
+}
+
+// Check measures text in characters rather than bytes and applies the rules
+// to every word in it. Words are compared after normalization, so
+// punctuation, case and accents don't hide a match.
+func (e *Engine) Check(text string) (Result, error) {
+	if utf8.RuneCountInString(text) > e.maxRunes {
+		return Result{}, ErrTooLong
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	res := Result{}
+	var b strings.Builder
+
+	for len(text) > 0 {
+		n := wordLength(text)
+		if n == 0 {
+			_, size := utf8.DecodeRuneInString(text)
+			b.WriteString(text[:size])
+			text = text[size:]
+			continue
+		}
+
+		word := text[:n]
+		text = text[n:]
+
+		normalized := Normalize(word)
+		action, ok := e.rules[normalized]
+		if !ok {
+			b.WriteString(word)
+			continue
+		}
+
+		res.Matches = append(res.Matches, normalized)
+		switch action {
+		case ActionReject:
+			return Result{Matches: res.Matches}, ErrRejected
+		case ActionFlag:
+			res.Flagged = true
+			b.WriteString(word)
+		default:
+			b.WriteString(mask)
+		}
+	}
+
+	res.Text = b.String()
+	return res, nil
+}
+
+// Normalize folds a word to the form rules are compared in: lower case,
+// full-width letters replaced by their ASCII form, accents removed and
+// anything that isn't a letter or digit dropped.
+func Normalize(word string) string {
+	var b strings.Builder
+	for _, r := range word {
+		if r >= 0xFF01 && r <= 0xFF5E {
+			r -= 0xFEE0
+		}
+		r = unicode.ToLower(r)
+		if base, ok := accents[r]; ok {
+			r = base
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// wordLength returns the length in bytes of the word at the start of text,
+// or zero if text doesn't start with a word.
+func wordLength(text string) int {
+	n := 0
+	for n < len(text) {
+		r, size := utf8.DecodeRuneInString(text[n:])
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r) {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+func severity(a Action) int {
+	switch a {
+	case ActionMask:
+		return 1
+	case ActionFlag:
+		return 2
+	case ActionReject:
+		return 3
+	}
+	return 0
+}
+
+// accents maps precomposed Latin letters to their unaccented base letter.
+var accents = map[rune]rune{
+	'à': 'a', 'á': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a', 'å': 'a', 'ā': 'a', 'ă': 'a', 'ą': 'a',
+	'ç': 'c', 'ć': 'c', 'ĉ': 'c', 'ċ': 'c', 'č': 'c',
+	'ď': 'd', 'đ': 'd',
+	'è': 'e', 'é': 'e', 'ê': 'e', 'ë': 'e', 'ē': 'e', 'ĕ': 'e', 'ė': 'e', 'ę': 'e', 'ě': 'e',
+	'ĝ': 'g', 'ğ': 'g', 'ġ': 'g', 'ģ': 'g',
+	'ĥ': 'h', 'ħ': 'h',
+	'ì': 'i', 'í': 'i', 'î': 'i', 'ï': 'i', 'ĩ': 'i', 'ī': 'i', 'ĭ': 'i', 'į': 'i', 'ı': 'i',
+	'ĵ': 'j',
+	'ķ': 'k',
+	'ĺ': 'l', 'ļ': 'l', 'ľ': 'l', 'ŀ': 'l', 'ł': 'l',
+	'ñ': 'n', 'ń': 'n', 'ņ': 'n', 'ň': 'n',
+	'ò': 'o', 'ó': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o', 'ø': 'o', 'ō': 'o', 'ŏ': 'o', 'ő': 'o',
+	'ŕ': 'r', 'ŗ': 'r', 'ř': 'r',
+	'ś': 's', 'ŝ': 's', 'ş': 's', 'š': 's',
+	'ţ': 't', 'ť': 't', 'ŧ': 't',
+	'ù': 'u', 'ú': 'u', 'û': 'u', 'ü': 'u', 'ũ': 'u', 'ū': 'u', 'ŭ': 'u', 'ů': 'u', 'ű': 'u', 'ų': 'u',
+	'ŵ': 'w',
+	'ý': 'y', 'ÿ': 'y', 'ŷ': 'y',
+	'ź': 'z', 'ż': 'z', 'ž': 'z',
+}
@@ -0,0 +1,138 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type staticSource []Rule
+
+func (s staticSource) Rules(ctx context.Context) ([]Rule, error) {
+	return s, nil
+}
+
+func newTestEngine(t *testing.T) *Engine {
+	t.Helper()
+	e := NewEngine(140, staticSource{
+		{Word: "kerfuffle", Action: ActionMask},
+		{Word: "Fornax", Action: ActionMask},
+		{Word: "sharbert", Action: ActionFlag},
+		{Word: "zorp", Action: ActionReject},
+	})
+	if err := e.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	return e
+}
+
+func TestCheck(t *testing.T) {
+	e := newTestEngine(t)
+
+	tests := []struct {
+		name        string
+		text        string
+		wantText    string
+		wantFlagged bool
+		wantErr     error
+	}{
+		{
+			name:     "Clean text",
+			text:     "This is a nice chirp",
+			wantText: "This is a nice chirp",
+		},
+		{
+			name:     "Masks words regardless of case",
+			text:     "What a Kerfuffle today",
+			wantText: "What a **** today",
+		},
+		{
+			name:     "Masks words next to punctuation",
+			text:     "Kerfuffle! Look at fornax, again.",
+			wantText: "****! Look at ****, again.",
+		},
+		{
+			name:     "Masks accented and full-width words",
+			text:     "kérfüffle and ｆｏｒｎａｘ",
+			wantText: "**** and ****",
+		},
+		{
+			name:        "Flags without masking",
+			text:        "I love sharbert",
+			wantText:    "I love sharbert",
+			wantFlagged: true,
+		},
+		{
+			name:    "Rejects",
+			text:    "zorp you",
+			wantErr: ErrRejected,
+		},
+		{
+			name:     "Length is measured in characters",
+			text:     strings.Repeat("é", 140),
+			wantText: strings.Repeat("é", 140),
+		},
+		{
+			name:    "Too long",
+			text:    strings.Repeat("a", 141),
+			wantErr: ErrTooLong,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := e.Check(tt.text)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if res.Text != tt.wantText {
+				t.Errorf("Check() text = %q, want %q", res.Text, tt.wantText)
+			}
+			if res.Flagged != tt.wantFlagged {
+				t.Errorf("Check() flagged = %v, want %v", res.Flagged, tt.wantFlagged)
+			}
+		})
+	}
+}
+
+func TestReloadStrictestActionWins(t *testing.T) {
+	e := NewEngine(140,
+		staticSource{{Word: "kerfuffle", Action: ActionReject}},
+		staticSource{{Word: "KERFUFFLE", Action: ActionMask}},
+	)
+	if err := e.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	if _, err := e.Check("kerfuffle"); !errors.Is(err, ErrRejected) {
+		t.Errorf("Check() error = %v, want %v", err, ErrRejected)
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	content := "# banned words\nkerfuffle\n\nsharbert reject\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := FileSource{Path: path}.Rules(context.Background())
+	if err != nil {
+		t.Fatalf("Rules() error = %v", err)
+	}
+
+	want := []Rule{
+		{Word: "kerfuffle", Action: ActionMask},
+		{Word: "sharbert", Action: ActionReject},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("Rules() = %v, want %v", rules, want)
+	}
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalDisk(t *testing.T) {
+	ctx := context.Background()
+	d, err := NewLocalDisk(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalDisk() error = %v", err)
+	}
+
+	if err := d.Put(ctx, "media/a.txt", strings.NewReader("hello")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	f, err := d.Open(ctx, "media/a.txt")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	got, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if string(got) != "hello" {
+		t.Errorf("Open() content = %q, want %q", got, "hello")
+	}
+
+	if err := d.Delete(ctx, "media/a.txt"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := d.Open(ctx, "media/a.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open() after Delete() error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestLocalDiskInvalidKeys(t *testing.T) {
+	d, err := NewLocalDisk(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalDisk() error = %v", err)
+	}
+
+	for _, key := range []string{"", "/etc/passwd", "../secret", "media/../../secret"} {
+		t.Run(key, func(t *testing.T) {
+			err := d.Put(context.Background(), key, strings.NewReader("x"))
+			if !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("Put(%q) error = %v, want %v", key, err, ErrInvalidKey)
+			}
+		})
+	}
+}
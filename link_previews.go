@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/17xande/bd-chirpy/internal/database"
+	"github.com/17xande/bd-chirpy/internal/linkpreview"
+	"github.com/google/uuid"
+)
+
+const (
+	linkPreviewTimeout      = 5 * time.Second
+	linkPreviewMaxBytes     = 512 << 10
+	linkPreviewBatchSize    = 20
+	linkPreviewPollInterval = time.Minute
+)
+
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url"`
+	SiteName    string `json:"site_name"`
+}
+
+func linkPreviewFromDB(p database.LinkPreview) *LinkPreview {
+	return &LinkPreview{
+		URL:         p.Url,
+		Title:       p.Title,
+		Description: p.Description,
+		ImageURL:    p.ImageUrl,
+		SiteName:    p.SiteName,
+	}
+}
+
+// queueLinkPreview records the first link in chirp's body as waiting for a
+// preview, or drops the preview when the body no longer has links. Callers
+// run it in the transaction that writes the chirp and wake the worker once
+// that transaction commits.
+func queueLinkPreview(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	urls := linkpreview.ExtractURLs(chirp.Body)
+	if len(urls) == 0 {
+		if err := q.DeleteLinkPreview(ctx, chirp.ID); err != nil {
+			return fmt.Errorf("can't delete link preview: %w", err)
+		}
+		return nil
+	}
+
+	err := q.SetLinkPreviewURL(ctx, database.SetLinkPreviewURLParams{
+		ChirpID: chirp.ID,
+		Url:     urls[0],
+	})
+	if err != nil {
+		return fmt.Errorf("can't queue link preview: %w", err)
+	}
+
+	return nil
+}
+
+// linkPreviewWorker fetches link previews in the background, so posting a
+// chirp never waits on a third-party site. Pending previews live in the
+// database; the worker also polls for them, which picks up work left over
+// from before a restart.
+type linkPreviewWorker struct {
+	db      *database.Queries
+	fetcher linkpreview.Fetcher
+	wake    chan struct{}
+}
+
+func newLinkPreviewWorker(db *database.Queries, fetcher linkpreview.Fetcher) *linkPreviewWorker {
+	return &linkPreviewWorker{
+		db:      db,
+		fetcher: fetcher,
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// notify tells the worker there are new previews to fetch. It never blocks.
+func (w *linkPreviewWorker) notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (w *linkPreviewWorker) run(ctx context.Context) {
+	ticker := time.NewTicker(linkPreviewPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.fetchPending(ctx); err != nil {
+			log.Printf("Error fetching link previews: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *linkPreviewWorker) fetchPending(ctx context.Context) error {
+	for {
+		pending, err := w.db.GetPendingLinkPreviews(ctx, linkPreviewBatchSize)
+		if err != nil {
+			return err
+		}
+
+		progress := false
+		for _, p := range pending {
+			if w.fetch(ctx, p.ChirpID, p.Url) {
+				progress = true
+			}
+		}
+
+		// Rows that couldn't be updated are still pending and would be
+		// returned again straight away, so wait for the next tick instead.
+		if len(pending) < linkPreviewBatchSize || !progress {
+			return nil
+		}
+	}
+}
+
+// fetch stores the preview of url, or marks it as failed so it isn't retried
+// forever. Both updates match on the URL too, so a result for a link the
+// chirp no longer contains is discarded. It reports whether the preview was
+// updated at all.
+func (w *linkPreviewWorker) fetch(ctx context.Context, chirpID uuid.UUID, url string) bool {
+	fetchCtx, cancel := context.WithTimeout(ctx, linkPreviewTimeout)
+	defer cancel()
+
+	preview, err := w.fetcher.Fetch(fetchCtx, url)
+	if err != nil {
+		log.Printf("Can't fetch link preview for %s: %v", url, err)
+		return w.fail(ctx, chirpID, url)
+	}
+
+	err = w.db.CompleteLinkPreview(ctx, database.CompleteLinkPreviewParams{
+		ChirpID:     chirpID,
+		Url:         url,
+		Title:       preview.Title,
+		Description: preview.Description,
+		ImageUrl:    preview.ImageURL,
+		SiteName:    preview.SiteName,
+	})
+	if err != nil {
+		log.Printf("Error storing link preview for %s: %v", url, err)
+		return w.fail(ctx, chirpID, url)
+	}
+	return true
+}
+
+func (w *linkPreviewWorker) fail(ctx context.Context, chirpID uuid.UUID, url string) bool {
+	err := w.db.FailLinkPreview(ctx, database.FailLinkPreviewParams{
+		ChirpID: chirpID,
+		Url:     url,
+	})
+	if err != nil {
+		log.Printf("Error marking link preview as failed: %v", err)
+		return false
+	}
+	return true
+}
@@ -1,23 +1,38 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/17xande/bd-chirpy/internal/database"
+	"github.com/17xande/bd-chirpy/internal/linkpreview"
+	"github.com/17xande/bd-chirpy/internal/mailer"
+	"github.com/17xande/bd-chirpy/internal/moderation"
+	"github.com/17xande/bd-chirpy/internal/storage"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
 type apiConfig struct {
 	db             *database.Queries
+	conn           *sql.DB
 	fileserverHits atomic.Int32
 	platform       string
 	secret         string
 	polkaKey       string
+	adminKey       string
+	moderation     *moderation.Engine
+	media          storage.Storage
+	linkPreviews   *linkPreviewWorker
+	restoreWindow  time.Duration
+	mailer         mailer.Mailer
+	baseURL        string
 }
 
 func main() {
@@ -45,33 +60,159 @@ func main() {
 		log.Fatal("POLKA_KEY must be set")
 	}
 
+	adminKey := os.Getenv("ADMIN_API_KEY")
+
+	restoreWindow := defaultRestoreWindow
+	if window := os.Getenv("CHIRP_RESTORE_WINDOW"); window != "" {
+		d, err := time.ParseDuration(window)
+		if err != nil || d < 0 {
+			log.Fatalf("CHIRP_RESTORE_WINDOW must be a non-negative duration, got %q", window)
+		}
+		restoreWindow = d
+	}
+
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
 		log.Fatalf("Error opening database: %v", err)
 	}
 
 	dbQueries := database.New(db)
+
+	wordSources := []moderation.Source{dbWordSource{db: dbQueries}}
+	if wordsFile := os.Getenv("MODERATION_WORDS_FILE"); wordsFile != "" {
+		wordSources = append(wordSources, moderation.FileSource{Path: wordsFile})
+	}
+	moderationEngine := moderation.NewEngine(maxChirpLength, wordSources...)
+	if err := moderationEngine.Reload(context.Background()); err != nil {
+		log.Fatalf("Error loading moderation rules: %v", err)
+	}
+
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = "./media"
+	}
+	mediaStorage, err := storage.NewLocalDisk(mediaDir)
+	if err != nil {
+		log.Fatalf("Error opening media storage: %v", err)
+	}
+
+	baseURL := os.Getenv("BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:" + port
+	}
+
+	mailFrom := os.Getenv("MAIL_FROM")
+	if mailFrom == "" {
+		mailFrom = "chirpy@localhost"
+	}
+	var mail mailer.Mailer
+	smtpAddr := os.Getenv("SMTP_ADDR")
+	mailDir := os.Getenv("MAIL_DIR")
+	switch {
+	case smtpAddr != "":
+		mail, err = mailer.NewSMTP(smtpAddr, mailFrom, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+		if err != nil {
+			log.Fatalf("Error configuring SMTP: %v", err)
+		}
+	case mailDir != "":
+		mail, err = mailer.NewDir(mailDir, mailFrom)
+		if err != nil {
+			log.Fatalf("Error opening mail directory: %v", err)
+		}
+	case platform == "dev":
+		// Printing mail puts verification and reset tokens in the logs, which
+		// is only acceptable on a developer's machine.
+		log.Println("SMTP_ADDR and MAIL_DIR aren't set, printing emails to stdout")
+		mail = &mailer.Writer{W: os.Stdout, From: mailFrom}
+	default:
+		log.Fatal("SMTP_ADDR or MAIL_DIR must be set outside dev")
+	}
+
+	linkPreviews := newLinkPreviewWorker(dbQueries, linkpreview.NewHTTPFetcher(linkPreviewTimeout, linkPreviewMaxBytes))
+	go linkPreviews.run(context.Background())
+
 	apiCfg := apiConfig{
 		fileserverHits: atomic.Int32{},
 		db:             dbQueries,
+		conn:           db,
 		platform:       platform,
 		secret:         secret,
 		polkaKey:       polkaKey,
+		adminKey:       adminKey,
+		moderation:     moderationEngine,
+		media:          mediaStorage,
+		linkPreviews:   linkPreviews,
+		restoreWindow:  restoreWindow,
+		mailer:         mail,
+		baseURL:        strings.TrimSuffix(baseURL, "/"),
 	}
 
+	go apiCfg.runScheduler(context.Background())
+	go apiCfg.runPurger(context.Background())
+
 	mux := http.NewServeMux()
 	fsHandler := apiCfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(filepathRoot))))
 	mux.Handle("/app/", fsHandler)
 	mux.HandleFunc("GET /admin/metrics", apiCfg.handlerMetrics)
 	mux.HandleFunc("POST /admin/reset", apiCfg.handlerReset)
+	mux.HandleFunc("GET /admin/moderation/words", apiCfg.middlewareAdmin(apiCfg.handlerModerationWordsGet))
+	mux.HandleFunc("POST /admin/moderation/words", apiCfg.middlewareAdmin(apiCfg.handlerModerationWordsSet))
+	mux.HandleFunc("DELETE /admin/moderation/words/{word}", apiCfg.middlewareAdmin(apiCfg.handlerModerationWordsDelete))
+	mux.HandleFunc("GET /admin/reports", apiCfg.middlewareAdmin(apiCfg.handlerAdminReportsGet))
+	mux.HandleFunc("POST /admin/chirps/{id}/approve", apiCfg.middlewareAdmin(apiCfg.handlerAdminChirpApprove))
+	mux.HandleFunc("POST /admin/chirps/{id}/hide", apiCfg.middlewareAdmin(apiCfg.handlerAdminChirpHide))
+	mux.HandleFunc("DELETE /admin/chirps/{id}", apiCfg.middlewareAdmin(apiCfg.handlerAdminChirpDelete))
 	mux.HandleFunc("GET /api/healthz", apiCfg.handlerReadiness)
 	mux.HandleFunc("GET /api/chirps", apiCfg.handlerChirpsGet)
+	mux.HandleFunc("GET /api/chirps/search", apiCfg.handlerChirpsSearch)
+	mux.HandleFunc("GET /api/chirps/scheduled", apiCfg.handlerScheduledChirpsGet)
+	mux.HandleFunc("POST /api/chirps/{id}/cancel", apiCfg.handlerScheduledChirpCancel)
 	mux.HandleFunc("GET /api/chirps/{id}", apiCfg.handlerChirpGet)
+	mux.HandleFunc("PUT /api/chirps/{id}", apiCfg.handlerChirpUpdate)
 	mux.HandleFunc("DELETE /api/chirps/{id}", apiCfg.handlerChirpDelete)
+	mux.HandleFunc("GET /api/chirps/{id}/history", apiCfg.handlerChirpHistory)
+	mux.HandleFunc("POST /api/chirps/{id}/restore", apiCfg.handlerChirpRestore)
+	mux.HandleFunc("POST /api/chirps/{id}/pin", apiCfg.handlerChirpPin)
+	mux.HandleFunc("DELETE /api/chirps/{id}/pin", apiCfg.handlerChirpUnpin)
+	mux.HandleFunc("GET /api/chirps/{id}/replies", apiCfg.handlerChirpReplies)
+	mux.HandleFunc("GET /api/chirps/{id}/thread", apiCfg.handlerChirpThread)
+	mux.HandleFunc("POST /api/chirps/{id}/likes", apiCfg.handlerLikeCreate)
+	mux.HandleFunc("DELETE /api/chirps/{id}/likes", apiCfg.handlerLikeDelete)
+	mux.HandleFunc("POST /api/chirps/{id}/rechirp", apiCfg.handlerRechirpCreate)
+	mux.HandleFunc("POST /api/chirps/{id}/report", apiCfg.handlerChirpReport)
+	mux.HandleFunc("DELETE /api/chirps/{id}/rechirp", apiCfg.handlerRechirpDelete)
 	mux.HandleFunc("POST /api/chirps", apiCfg.handlerChirpsCreate)
+	mux.HandleFunc("POST /api/drafts", apiCfg.handlerDraftsCreate)
+	mux.HandleFunc("GET /api/drafts", apiCfg.handlerDraftsGet)
+	mux.HandleFunc("GET /api/drafts/{id}", apiCfg.handlerDraftGet)
+	mux.HandleFunc("PUT /api/drafts/{id}", apiCfg.handlerDraftUpdate)
+	mux.HandleFunc("DELETE /api/drafts/{id}", apiCfg.handlerDraftDelete)
+	mux.HandleFunc("POST /api/drafts/{id}/publish", apiCfg.handlerDraftPublish)
+	mux.HandleFunc("POST /api/password/forgot", apiCfg.handlerPasswordForgot)
+	mux.HandleFunc("POST /api/password/reset", apiCfg.handlerPasswordReset)
 	mux.HandleFunc("POST /api/users", apiCfg.handlerUsersCreate)
 	mux.HandleFunc("PUT /api/users", apiCfg.handlerUsersUpdate)
+	mux.HandleFunc("PATCH /api/users/me", apiCfg.handlerUsersMePatch)
+	mux.HandleFunc("GET /api/users/verify", apiCfg.handlerEmailVerify)
+	mux.HandleFunc("POST /api/users/verify", apiCfg.handlerEmailVerifyResend)
+	mux.HandleFunc("PUT /api/users/me/handle", apiCfg.handlerUserHandleSet)
+	mux.HandleFunc("POST /api/users/{id}/follow", apiCfg.handlerFollowCreate)
+	mux.HandleFunc("DELETE /api/users/{id}/follow", apiCfg.handlerFollowDelete)
+	mux.HandleFunc("GET /api/users/{id}", apiCfg.handlerUserGet)
+	mux.HandleFunc("GET /api/users/{id}/{resource}", apiCfg.handlerUserResourceGet)
+	mux.HandleFunc("POST /api/media", apiCfg.handlerMediaUpload)
+	mux.HandleFunc("GET /api/media/{id}", apiCfg.handlerMediaGet)
+	mux.HandleFunc("GET /api/media/{id}/thumbnail", apiCfg.handlerMediaThumbnailGet)
+	mux.HandleFunc("GET /api/timeline", apiCfg.handlerTimeline)
+	mux.HandleFunc("GET /api/hashtags/trending", apiCfg.handlerHashtagsTrending)
+	mux.HandleFunc("GET /api/hashtags/{tag}/chirps", apiCfg.handlerHashtagChirps)
 	mux.HandleFunc("POST /api/login", apiCfg.handlerUserLogin)
+	mux.HandleFunc("POST /api/login/mfa", apiCfg.handlerUserLoginMFA)
+	mux.HandleFunc("GET /api/mfa", apiCfg.handlerMFAGet)
+	mux.HandleFunc("POST /api/mfa/totp", apiCfg.handlerTOTPEnroll)
+	mux.HandleFunc("POST /api/mfa/totp/confirm", apiCfg.handlerTOTPConfirm)
+	mux.HandleFunc("DELETE /api/mfa/totp", apiCfg.handlerTOTPDisable)
+	mux.HandleFunc("POST /api/mfa/recovery-codes", apiCfg.handlerRecoveryCodesCreate)
 	mux.HandleFunc("POST /api/refresh", apiCfg.handlerGetRefreshToken)
 	mux.HandleFunc("POST /api/revoke", apiCfg.handlerRevokeToken)
 	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.handlerPolkaWebhook)
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+// pageCursor marks the last chirp of a page. The next page starts strictly
+// after it in (created_at, id) order.
+type pageCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// createdAt and id convert the cursor into query parameters. A nil cursor
+// yields NULLs, which the keyset queries treat as "start from the beginning".
+func (c *pageCursor) createdAt() sql.NullTime {
+	if c == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: c.CreatedAt, Valid: true}
+}
+
+func (c *pageCursor) id() uuid.NullUUID {
+	if c == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: c.ID, Valid: true}
+}
+
+func encodeCursor(c pageCursor) string {
+	raw := c.CreatedAt.Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("can't decode cursor: %w", err)
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return pageCursor{}, errors.New("malformed cursor")
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("can't parse cursor time: %w", err)
+	}
+
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("can't parse cursor id: %w", err)
+	}
+
+	return pageCursor{CreatedAt: t, ID: uid}, nil
+}
+
+// parsePageParams reads the limit and cursor query parameters. The returned
+// cursor is nil when the client asked for the first page.
+func parsePageParams(query url.Values) (int32, *pageCursor, error) {
+	limit, err := parseLimit(query)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	c := query.Get("cursor")
+	if c == "" {
+		return limit, nil, nil
+	}
+
+	cursor, err := decodeCursor(c)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return limit, &cursor, nil
+}
+
+func parseLimit(query url.Values) (int32, error) {
+	limit := defaultPageSize
+	if l := query.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("invalid limit: %q", l)
+		}
+		limit = min(n, maxPageSize)
+	}
+	return int32(limit), nil
+}
+
+// setNextPageLink points the client at the page following cursor, keeping
+// every other query parameter of the current request.
+func setNextPageLink(w http.ResponseWriter, r *http.Request, cursor pageCursor) {
+	setNextLink(w, r, encodeCursor(cursor))
+}
+
+func setNextLink(w http.ResponseWriter, r *http.Request, cursor string) {
+	query := r.URL.Query()
+	query.Set("cursor", cursor)
+	next := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", next.String()))
+}
+
+// parseTimeParam parses an RFC 3339 query parameter. An empty value yields a
+// NULL, which the listing queries treat as "no bound".
+func parseTimeParam(value string) (sql.NullTime, error) {
+	if value == "" {
+		return sql.NullTime{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+
+	return sql.NullTime{Time: t.UTC(), Valid: true}, nil
+}
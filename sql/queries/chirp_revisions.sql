@@ -0,0 +1,9 @@
+-- name: CreateChirpRevision :one
+insert into chirp_revisions(id, chirp_id, body, created_at, replaced_at)
+values (gen_random_uuid(), $1, $2, $3, now())
+returning *;
+
+-- name: GetChirpRevisions :many
+select * from chirp_revisions
+where chirp_id = $1
+order by replaced_at desc;
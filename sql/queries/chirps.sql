@@ -1,6 +1,6 @@
 -- name: CreateChirps :one
-insert into chirps(id, created_at, updated_at, body, user_id)
-values (gen_random_uuid(), now(), now(), $1, $2)
+insert into chirps(id, created_at, updated_at, body, user_id, parent_id, publish_at, published)
+values (gen_random_uuid(), now(), now(), $1, $2, $3, sqlc.narg('publish_at'), sqlc.narg('publish_at')::timestamp is null)
 returning *;
 
 -- name: GetChirps :many
@@ -16,6 +16,202 @@ order by created_at;
 select * from chirps
 where id = $1;
 
--- name: DeleteChirp :execrows
+-- name: SoftDeleteChirp :execrows
+update chirps
+set deleted_at = now(), pinned_at = null
+where id = $1 and user_id = $2 and deleted_at is null;
+
+-- name: RestoreChirp :one
+update chirps
+set deleted_at = null
+where id = $1 and user_id = $2 and deleted_at >= now() - make_interval(secs => sqlc.arg('window_seconds')::float8)
+returning *;
+
+-- name: PurgeDeletedChirps :execrows
+delete from chirps
+where deleted_at < now() - make_interval(secs => sqlc.arg('window_seconds')::float8);
+
+-- name: ListChirpsAsc :many
+select * from chirps
+where (sqlc.narg('author_id')::uuid is null or user_id = sqlc.narg('author_id'))
+  and (sqlc.narg('since')::timestamp is null or created_at >= sqlc.narg('since'))
+  and (sqlc.narg('until')::timestamp is null or created_at < sqlc.narg('until'))
+  and (
+    sqlc.narg('after_created_at')::timestamp is null
+    or (created_at, id) > (sqlc.narg('after_created_at'), sqlc.narg('after_id')::uuid)
+  )
+  and deleted_at is null
+  and ((moderation_status <> 'hidden' and published) or user_id = sqlc.narg('viewer_id'))
+  and (not sqlc.arg('exclude_pinned')::bool or pinned_at is null)
+order by created_at, id
+limit sqlc.arg('page_size')::int;
+
+-- name: ListChirpsDesc :many
+select * from chirps
+where (sqlc.narg('author_id')::uuid is null or user_id = sqlc.narg('author_id'))
+  and (sqlc.narg('since')::timestamp is null or created_at >= sqlc.narg('since'))
+  and (sqlc.narg('until')::timestamp is null or created_at < sqlc.narg('until'))
+  and (
+    sqlc.narg('after_created_at')::timestamp is null
+    or (created_at, id) < (sqlc.narg('after_created_at'), sqlc.narg('after_id')::uuid)
+  )
+  and deleted_at is null
+  and ((moderation_status <> 'hidden' and published) or user_id = sqlc.narg('viewer_id'))
+  and (not sqlc.arg('exclude_pinned')::bool or pinned_at is null)
+order by created_at desc, id desc
+limit sqlc.arg('page_size')::int;
+
+-- name: SearchChirps :many
+-- The body is HTML-escaped before it's highlighted, so the snippet is safe to
+-- render as HTML.
+select
+  matches.*,
+  ts_headline(
+    'english',
+    replace(replace(replace(matches.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+    to_tsquery('english', sqlc.arg('query')),
+    'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=5'
+  )::text as snippet
+from (
+  select chirps.*, ts_rank(chirps.body_search, query)::real as rank
+  from chirps, to_tsquery('english', sqlc.arg('query')) query
+  where chirps.body_search @@ query
+    and chirps.moderation_status <> 'hidden'
+    and chirps.published
+    and chirps.deleted_at is null
+) matches
+where sqlc.narg('after_rank')::real is null
+  or (matches.rank, matches.created_at, matches.id) < (sqlc.narg('after_rank')::real, sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid)
+order by matches.rank desc, matches.created_at desc, matches.id desc
+limit sqlc.arg('page_size')::int;
+
+-- name: GetChirpForUpdate :one
+select * from chirps
+where id = $1
+for update;
+
+-- name: UpdateChirp :one
+update chirps
+set body = $1, updated_at = now()
+where id = $2 and user_id = $3
+returning *;
+
+-- name: GetReplies :many
+select * from chirps
+where parent_id = $1 and moderation_status <> 'hidden' and published
+  and deleted_at is null
+order by created_at, id;
+
+-- name: GetThread :many
+with recursive ancestors as (
+  select chirps.id, chirps.parent_id from chirps
+  where chirps.id = $1
+  union all
+  select c.id, c.parent_id from chirps c
+  join ancestors a on c.id = a.parent_id
+),
+thread as (
+  select ancestors.id from ancestors
+  where ancestors.parent_id is null
+  union all
+  select c.id from chirps c
+  join thread t on c.parent_id = t.id
+)
+select * from chirps
+where id in (select thread.id from thread)
+order by created_at, id;
+
+-- name: CountReplies :many
+select parent_id, count(*) as reply_count from chirps
+where moderation_status <> 'hidden'
+  and published
+  and deleted_at is null
+  and parent_id = any(sqlc.arg('chirp_ids')::uuid[])
+group by parent_id;
+
+-- name: GetChirpsByIDs :many
+select * from chirps
+where id = any(sqlc.arg('ids')::uuid[]);
+
+-- name: CreateRechirp :one
+insert into chirps(id, created_at, updated_at, body, user_id, rechirp_of_id, is_rechirp)
+values (gen_random_uuid(), now(), now(), $1, $2, $3, true)
+returning *;
+
+-- name: DeleteRechirp :execrows
 delete from chirps
-where id = $1 and user_id = $2;
+where user_id = $1 and rechirp_of_id = $2 and is_rechirp and body = '';
+
+-- name: FlagChirp :exec
+update chirps
+set moderation_status = 'flagged'
+where id = $1 and moderation_status = 'visible';
+
+-- name: SetChirpModerationStatus :one
+update chirps
+set moderation_status = $1
+where id = $2
+returning *;
+
+-- name: GetChirpsByModerationStatus :many
+select * from chirps
+where moderation_status = $1 and deleted_at is null
+order by created_at, id;
+
+-- name: DeleteChirpByID :execrows
+delete from chirps
+where id = $1;
+
+-- name: GetScheduledChirps :many
+select * from chirps
+where user_id = $1 and not published and deleted_at is null
+order by publish_at, id;
+
+-- name: CancelScheduledChirp :execrows
+delete from chirps
+where id = $1 and user_id = $2 and not published;
+
+-- name: PublishDueChirps :many
+update chirps
+set published = true, created_at = publish_at, updated_at = publish_at
+where not published and deleted_at is null and publish_at <= $1
+returning *;
+
+-- name: GetPinnedChirps :many
+select * from chirps
+where user_id = $1 and pinned_at is not null and deleted_at is null
+order by pinned_at desc, id desc;
+
+-- name: CountPinnedChirps :one
+select count(*) from chirps
+where user_id = $1 and pinned_at is not null and deleted_at is null;
+
+-- name: PinChirp :execrows
+update chirps
+set pinned_at = now()
+where id = $1 and user_id = $2 and pinned_at is null;
+
+-- name: UnpinChirp :execrows
+update chirps
+set pinned_at = null
+where id = $1 and user_id = $2 and pinned_at is not null;
+
+-- name: CountUserChirps :one
+select count(*) from chirps
+where user_id = $1
+  and published and deleted_at is null
+  and moderation_status <> 'hidden';
+
+-- name: GetChirpsMentioning :many
+select chirps.* from chirps
+join chirp_mentions on chirp_mentions.chirp_id = chirps.id
+where chirp_mentions.user_id = sqlc.arg('user_id')
+  and chirps.moderation_status <> 'hidden'
+  and chirps.published
+  and chirps.deleted_at is null
+  and (
+    sqlc.narg('after_created_at')::timestamp is null
+    or (chirps.created_at, chirps.id) < (sqlc.narg('after_created_at'), sqlc.narg('after_id')::uuid)
+  )
+order by chirps.created_at desc, chirps.id desc
+limit sqlc.arg('page_size')::int;
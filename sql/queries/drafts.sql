@@ -0,0 +1,28 @@
+-- name: CreateDraft :one
+insert into drafts(id, user_id, body, created_at, updated_at)
+values (gen_random_uuid(), $1, $2, now(), now())
+returning *;
+
+-- name: GetDrafts :many
+select * from drafts
+where user_id = $1
+order by updated_at desc, id desc;
+
+-- name: GetDraft :one
+select * from drafts
+where id = $1 and user_id = $2;
+
+-- name: GetDraftForUpdate :one
+select * from drafts
+where id = $1 and user_id = $2
+for update;
+
+-- name: UpdateDraft :one
+update drafts
+set body = $1, updated_at = now()
+where id = $2 and user_id = $3
+returning *;
+
+-- name: DeleteDraft :execrows
+delete from drafts
+where id = $1 and user_id = $2;
@@ -0,0 +1,15 @@
+-- name: CreateEmailVerificationToken :exec
+insert into email_verification_tokens (token_hash, user_id, email, created_at, expires_at)
+values ($1, $2, $3, now(), $4);
+
+-- name: DeleteEmailVerificationTokens :exec
+delete from email_verification_tokens
+where user_id = $1;
+
+-- name: UseEmailVerificationToken :one
+update email_verification_tokens
+set used_at = now()
+where token_hash = $1
+  and used_at is null
+  and expires_at > sqlc.arg('now')
+returning user_id, email;
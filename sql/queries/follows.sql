@@ -0,0 +1,32 @@
+-- name: CreateFollow :exec
+insert into follows(follower_id, followee_id, created_at)
+values ($1, $2, now())
+on conflict do nothing;
+
+-- name: DeleteFollow :execrows
+delete from follows
+where follower_id = $1 and followee_id = $2;
+
+-- name: GetFollowers :many
+select * from follows
+where followee_id = $1
+order by created_at desc;
+
+-- name: GetFollowing :many
+select * from follows
+where follower_id = $1
+order by created_at desc;
+
+-- name: GetTimeline :many
+select chirps.* from chirps
+join follows on follows.followee_id = chirps.user_id
+where chirps.moderation_status <> 'hidden'
+  and chirps.published
+  and chirps.deleted_at is null
+  and follows.follower_id = sqlc.arg('user_id')
+  and (
+    sqlc.narg('after_created_at')::timestamp is null
+    or (chirps.created_at, chirps.id) < (sqlc.narg('after_created_at'), sqlc.narg('after_id')::uuid)
+  )
+order by chirps.created_at desc, chirps.id desc
+limit sqlc.arg('page_size')::int;
@@ -0,0 +1,51 @@
+-- name: UpsertHashtag :one
+insert into hashtags(id, tag, created_at)
+values (gen_random_uuid(), $1, now())
+on conflict (tag) do update set tag = excluded.tag
+returning *;
+
+-- name: AddChirpHashtag :exec
+insert into chirp_hashtags(chirp_id, hashtag_id, created_at)
+values ($1, $2, now())
+on conflict do nothing;
+
+-- name: DeleteChirpHashtags :exec
+delete from chirp_hashtags
+where chirp_id = $1;
+
+-- name: AddChirpMention :exec
+insert into chirp_mentions(chirp_id, user_id, created_at)
+select sqlc.arg('chirp_id')::uuid, users.id, now() from users
+where lower(users.handle) = lower(sqlc.arg('handle'))
+on conflict do nothing;
+
+-- name: DeleteChirpMentions :exec
+delete from chirp_mentions
+where chirp_id = $1;
+
+-- name: GetChirpsByHashtag :many
+select chirps.* from chirps
+join chirp_hashtags on chirp_hashtags.chirp_id = chirps.id
+join hashtags on hashtags.id = chirp_hashtags.hashtag_id
+where chirps.moderation_status <> 'hidden'
+  and chirps.published
+  and chirps.deleted_at is null
+  and hashtags.tag = sqlc.arg('tag')
+  and (
+    sqlc.narg('after_created_at')::timestamp is null
+    or (chirps.created_at, chirps.id) < (sqlc.narg('after_created_at'), sqlc.narg('after_id')::uuid)
+  )
+order by chirps.created_at desc, chirps.id desc
+limit sqlc.arg('page_size')::int;
+
+-- name: GetTrendingHashtags :many
+select hashtags.tag, count(*) as chirp_count from chirp_hashtags
+join hashtags on hashtags.id = chirp_hashtags.hashtag_id
+join chirps on chirps.id = chirp_hashtags.chirp_id
+where chirps.moderation_status <> 'hidden'
+  and chirps.published
+  and chirps.deleted_at is null
+  and chirps.created_at >= now() - make_interval(secs => sqlc.arg('window_seconds')::float8)
+group by hashtags.tag
+order by chirp_count desc, hashtags.tag
+limit sqlc.arg('page_size')::int;
@@ -0,0 +1,18 @@
+-- name: CreateLike :exec
+insert into likes(user_id, chirp_id, created_at)
+values ($1, $2, now())
+on conflict do nothing;
+
+-- name: DeleteLike :execrows
+delete from likes
+where user_id = $1 and chirp_id = $2;
+
+-- name: CountLikes :many
+select chirp_id, count(*) as like_count from likes
+where chirp_id = any(sqlc.arg('chirp_ids')::uuid[])
+group by chirp_id;
+
+-- name: GetLikedChirpIDs :many
+select chirp_id from likes
+where user_id = sqlc.arg('user_id')
+  and chirp_id = any(sqlc.arg('chirp_ids')::uuid[]);
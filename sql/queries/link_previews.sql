@@ -0,0 +1,44 @@
+-- name: SetLinkPreviewURL :exec
+insert into link_previews(chirp_id, url, created_at)
+values ($1, $2, now())
+on conflict (chirp_id) do update
+set url = excluded.url,
+  status = 'pending',
+  title = '',
+  description = '',
+  image_url = '',
+  site_name = '',
+  created_at = excluded.created_at,
+  fetched_at = null
+where link_previews.url <> excluded.url;
+
+-- name: DeleteLinkPreview :exec
+delete from link_previews
+where chirp_id = $1;
+
+-- name: GetPendingLinkPreviews :many
+select * from link_previews
+where status = 'pending'
+order by created_at
+limit $1;
+
+-- name: CompleteLinkPreview :exec
+update link_previews
+set status = 'ready',
+  title = $3,
+  description = $4,
+  image_url = $5,
+  site_name = $6,
+  fetched_at = now()
+where chirp_id = $1 and url = $2;
+
+-- name: FailLinkPreview :exec
+update link_previews
+set status = 'failed',
+  fetched_at = now()
+where chirp_id = $1 and url = $2;
+
+-- name: GetLinkPreviewsByChirpIDs :many
+select * from link_previews
+where chirp_id = any(sqlc.arg('chirp_ids')::uuid[])
+  and status = 'ready';
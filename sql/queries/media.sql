@@ -0,0 +1,33 @@
+-- name: CreateMedia :one
+insert into media(id, user_id, content_type, size_bytes, storage_key, created_at, width, height, thumbnail_key)
+values ($1, $2, $3, $4, $5, now(), $6, $7, $8)
+returning *;
+
+-- name: GetMedia :one
+select * from media
+where id = $1;
+
+-- name: AttachMedia :execrows
+update media
+set chirp_id = sqlc.arg('chirp_id')
+where id = any(sqlc.arg('ids')::uuid[])
+  and user_id = sqlc.arg('user_id')
+  and chirp_id is null;
+
+-- name: GetMediaByChirpIDs :many
+select * from media
+where chirp_id = any(sqlc.arg('chirp_ids')::uuid[])
+order by created_at, id;
+
+-- name: DeleteChirpMedia :many
+delete from media
+where chirp_id = $1
+returning *;
+
+-- name: DeletePurgedChirpMedia :many
+delete from media
+where chirp_id in (
+  select id from chirps
+  where deleted_at < now() - make_interval(secs => sqlc.arg('window_seconds')::float8)
+)
+returning *;
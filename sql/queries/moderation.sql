@@ -0,0 +1,13 @@
+-- name: GetModerationWords :many
+select * from moderation_words
+order by word;
+
+-- name: UpsertModerationWord :one
+insert into moderation_words(word, action, created_at, updated_at)
+values ($1, $2, now(), now())
+on conflict (word) do update set action = excluded.action, updated_at = now()
+returning *;
+
+-- name: DeleteModerationWord :execrows
+delete from moderation_words
+where word = $1;
@@ -0,0 +1,15 @@
+-- name: CreatePasswordResetToken :exec
+insert into password_reset_tokens (token_hash, user_id, created_at, expires_at)
+values ($1, $2, now(), $3);
+
+-- name: DeletePasswordResetTokens :exec
+delete from password_reset_tokens
+where user_id = $1;
+
+-- name: UsePasswordResetToken :one
+update password_reset_tokens
+set used_at = now()
+where token_hash = $1
+  and used_at is null
+  and expires_at > sqlc.arg('now')
+returning user_id;
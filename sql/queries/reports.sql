@@ -0,0 +1,15 @@
+-- name: CreateChirpReport :exec
+insert into chirp_reports(id, chirp_id, reporter_id, reason, created_at)
+values (gen_random_uuid(), $1, $2, $3, now())
+on conflict (chirp_id, reporter_id) where resolved_at is null do nothing;
+
+-- name: GetOpenReports :many
+select * from chirp_reports
+where resolved_at is null
+  and chirp_id = any(sqlc.arg('chirp_ids')::uuid[])
+order by created_at;
+
+-- name: ResolveChirpReports :exec
+update chirp_reports
+set resolved_at = now()
+where chirp_id = $1 and resolved_at is null;
@@ -19,3 +19,8 @@ update refresh_tokens
 set updated_at = now(), revoked_at = now()
 where token = $1
 returning *;
+
+-- name: RevokeUserRefreshTokens :exec
+update refresh_tokens
+set updated_at = now(), revoked_at = now()
+where user_id = $1 and revoked_at is null;
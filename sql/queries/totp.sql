@@ -0,0 +1,59 @@
+-- name: StartUserTOTP :one
+insert into user_totp (user_id, secret, created_at)
+values ($1, $2, now())
+on conflict (user_id) do update
+set secret = excluded.secret, created_at = now(), last_used_step = 0
+where user_totp.confirmed_at is null
+returning *;
+
+-- name: GetUserTOTP :one
+select * from user_totp
+where user_id = $1;
+
+-- name: ConfirmUserTOTP :one
+update user_totp
+set confirmed_at = now(), last_used_step = $2
+where user_id = $1 and confirmed_at is null
+returning *;
+
+-- name: UseTOTPStep :execrows
+update user_totp
+set last_used_step = $2
+where user_id = $1 and last_used_step < $2;
+
+-- name: DeleteUserTOTP :exec
+delete from user_totp
+where user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+insert into recovery_codes (code_hash, user_id, created_at)
+values ($1, $2, now());
+
+-- name: DeleteRecoveryCodes :exec
+delete from recovery_codes
+where user_id = $1;
+
+-- name: UseRecoveryCode :execrows
+update recovery_codes
+set used_at = now()
+where code_hash = $1 and user_id = $2 and used_at is null;
+
+-- name: CountRecoveryCodes :one
+select count(*) from recovery_codes
+where user_id = $1 and used_at is null;
+
+-- name: RecordTOTPFailure :one
+update user_totp
+set failed_attempts = failed_attempts + 1
+where user_id = $1
+returning failed_attempts;
+
+-- name: LockUserTOTP :exec
+update user_totp
+set failed_attempts = 0, locked_until = $2
+where user_id = $1;
+
+-- name: ResetTOTPFailures :exec
+update user_totp
+set failed_attempts = 0
+where user_id = $1;
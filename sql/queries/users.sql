@@ -1,6 +1,6 @@
 -- name: CreateUser :one
-insert into users (id, created_at, updated_at, email, hashed_password)
-values (gen_random_uuid(), now(), now(), $1, $2)
+insert into users (id, created_at, updated_at, email, hashed_password, handle)
+values (gen_random_uuid(), now(), now(), $1, $2, $3)
 returning *;
 
 -- name: GetUserByEmail :one
@@ -9,7 +9,10 @@ where email = $1;
 
 -- name: UpdateUser :one
 update users
-set email = $1, hashed_password = $2, updated_at = now()
+set email = $1,
+  hashed_password = $2,
+  email_verified_at = case when email = $1 then email_verified_at end,
+  updated_at = now()
 where id = $3
 returning *;
 
@@ -18,3 +21,47 @@ update users
 set is_chirpy_red = true
 where id = $1
 returning *;
+
+-- name: GetUser :one
+select * from users
+where id = $1;
+
+-- name: GetUserForUpdate :one
+select * from users
+where id = $1
+for update;
+
+-- name: GetUserByHandle :one
+select * from users
+where lower(handle) = lower(sqlc.arg('handle'));
+
+-- name: SetUserHandle :one
+update users
+set handle = $1, updated_at = now()
+where id = $2
+returning *;
+
+-- name: UpdateUserProfile :one
+update users
+set email = coalesce(sqlc.narg('email'), email),
+  hashed_password = coalesce(sqlc.narg('hashed_password'), hashed_password),
+  display_name = coalesce(sqlc.narg('display_name'), display_name),
+  bio = coalesce(sqlc.narg('bio'), bio),
+  avatar_url = coalesce(sqlc.narg('avatar_url'), avatar_url),
+  email_verified_at = case
+    when coalesce(sqlc.narg('email'), email) = email then email_verified_at
+  end,
+  updated_at = now()
+where id = sqlc.arg('id')
+returning *;
+
+-- name: MarkUserEmailVerified :one
+update users
+set email_verified_at = now(), updated_at = now()
+where id = $1 and email = $2
+returning *;
+
+-- name: SetUserPassword :exec
+update users
+set hashed_password = $1, updated_at = now()
+where id = $2;
@@ -0,0 +1,7 @@
+-- +goose Up
+create index chirps_created_at_id_idx on chirps(created_at, id);
+create index chirps_user_id_created_at_id_idx on chirps(user_id, created_at, id);
+
+-- +goose Down
+drop index chirps_user_id_created_at_id_idx;
+drop index chirps_created_at_id_idx;
@@ -0,0 +1,11 @@
+-- +goose Up
+alter table chirps
+add body_search tsvector generated always as (to_tsvector('english', body)) stored;
+
+create index chirps_body_search_idx on chirps using gin(body_search);
+
+-- +goose Down
+drop index chirps_body_search_idx;
+
+alter table chirps
+drop column body_search;
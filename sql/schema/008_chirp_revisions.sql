@@ -0,0 +1,14 @@
+-- +goose Up
+create table chirp_revisions (
+  id uuid,
+  chirp_id uuid not null references chirps(id) on delete cascade,
+  body text not null,
+  created_at timestamp not null,
+  replaced_at timestamp not null,
+  primary key(id)
+);
+
+create index chirp_revisions_chirp_id_idx on chirp_revisions(chirp_id, replaced_at);
+
+-- +goose Down
+drop table chirp_revisions;
@@ -0,0 +1,9 @@
+-- +goose Up
+alter table chirps
+add parent_id uuid references chirps(id) on delete set null;
+
+create index chirps_parent_id_idx on chirps(parent_id, created_at, id);
+
+-- +goose Down
+alter table chirps
+drop column parent_id;
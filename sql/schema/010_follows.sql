@@ -0,0 +1,13 @@
+-- +goose Up
+create table follows (
+  follower_id uuid not null references users(id) on delete cascade,
+  followee_id uuid not null references users(id) on delete cascade,
+  created_at timestamp not null,
+  primary key(follower_id, followee_id),
+  check (follower_id <> followee_id)
+);
+
+create index follows_followee_id_idx on follows(followee_id, created_at);
+
+-- +goose Down
+drop table follows;
@@ -0,0 +1,12 @@
+-- +goose Up
+create table likes (
+  user_id uuid not null references users(id) on delete cascade,
+  chirp_id uuid not null references chirps(id) on delete cascade,
+  created_at timestamp not null,
+  primary key(user_id, chirp_id)
+);
+
+create index likes_chirp_id_idx on likes(chirp_id);
+
+-- +goose Down
+drop table likes;
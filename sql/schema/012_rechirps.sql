@@ -0,0 +1,14 @@
+-- +goose Up
+alter table chirps
+add rechirp_of_id uuid references chirps(id) on delete set null,
+add is_rechirp boolean not null default false;
+
+create unique index chirps_user_id_rechirp_of_id_idx on chirps(user_id, rechirp_of_id)
+where is_rechirp and body = '';
+
+-- +goose Down
+drop index chirps_user_id_rechirp_of_id_idx;
+
+alter table chirps
+drop column is_rechirp,
+drop column rechirp_of_id;
@@ -0,0 +1,30 @@
+-- +goose Up
+create table hashtags (
+  id uuid,
+  tag text not null unique,
+  created_at timestamp not null,
+  primary key(id)
+);
+
+create table chirp_hashtags (
+  chirp_id uuid not null references chirps(id) on delete cascade,
+  hashtag_id uuid not null references hashtags(id) on delete cascade,
+  created_at timestamp not null,
+  primary key(chirp_id, hashtag_id)
+);
+
+create index chirp_hashtags_hashtag_id_idx on chirp_hashtags(hashtag_id, created_at);
+
+create table chirp_mentions (
+  chirp_id uuid not null references chirps(id) on delete cascade,
+  handle text not null,
+  created_at timestamp not null,
+  primary key(chirp_id, handle)
+);
+
+create index chirp_mentions_handle_idx on chirp_mentions(handle, created_at);
+
+-- +goose Down
+drop table chirp_mentions;
+drop table chirp_hashtags;
+drop table hashtags;
@@ -0,0 +1,17 @@
+-- +goose Up
+create table moderation_words (
+  word text,
+  action text not null check (action in ('mask', 'flag', 'reject')),
+  created_at timestamp not null,
+  updated_at timestamp not null,
+  primary key(word)
+);
+
+insert into moderation_words(word, action, created_at, updated_at)
+values
+  ('kerfuffle', 'mask', now(), now()),
+  ('sharbert', 'mask', now(), now()),
+  ('fornax', 'mask', now(), now());
+
+-- +goose Down
+drop table moderation_words;
@@ -0,0 +1,26 @@
+-- +goose Up
+alter table chirps
+add moderation_status text not null default 'visible'
+  check (moderation_status in ('visible', 'flagged', 'hidden'));
+
+create index chirps_moderation_status_idx on chirps(moderation_status, created_at)
+where moderation_status <> 'visible';
+
+create table chirp_reports (
+  id uuid,
+  chirp_id uuid not null references chirps(id) on delete cascade,
+  reporter_id uuid references users(id) on delete set null,
+  reason text not null,
+  created_at timestamp not null,
+  resolved_at timestamp,
+  primary key(id)
+);
+
+create unique index chirp_reports_open_idx on chirp_reports(chirp_id, reporter_id)
+where resolved_at is null;
+
+-- +goose Down
+drop table chirp_reports;
+
+alter table chirps
+drop column moderation_status;
@@ -0,0 +1,16 @@
+-- +goose Up
+create table media (
+  id uuid,
+  user_id uuid not null references users(id) on delete cascade,
+  chirp_id uuid references chirps(id) on delete set null,
+  content_type text not null,
+  size_bytes bigint not null,
+  storage_key text not null,
+  created_at timestamp not null,
+  primary key(id)
+);
+
+create index media_chirp_id_idx on media(chirp_id);
+
+-- +goose Down
+drop table media;
@@ -0,0 +1,11 @@
+-- +goose Up
+alter table media
+  add column width integer not null default 0,
+  add column height integer not null default 0,
+  add column thumbnail_key text;
+
+-- +goose Down
+alter table media
+  drop column width,
+  drop column height,
+  drop column thumbnail_key;
@@ -0,0 +1,18 @@
+-- +goose Up
+create table link_previews (
+  chirp_id uuid not null references chirps(id) on delete cascade,
+  url text not null,
+  status text not null default 'pending' check (status in ('pending', 'ready', 'failed')),
+  title text not null default '',
+  description text not null default '',
+  image_url text not null default '',
+  site_name text not null default '',
+  created_at timestamp not null,
+  fetched_at timestamp,
+  primary key(chirp_id)
+);
+
+create index link_previews_pending_idx on link_previews(created_at) where status = 'pending';
+
+-- +goose Down
+drop table link_previews;
@@ -0,0 +1,11 @@
+-- +goose Up
+alter table chirps
+  add column publish_at timestamp,
+  add column published boolean not null default true;
+
+create index chirps_scheduled_idx on chirps(publish_at) where not published;
+
+-- +goose Down
+alter table chirps
+  drop column publish_at,
+  drop column published;
@@ -0,0 +1,14 @@
+-- +goose Up
+create table drafts (
+  id uuid,
+  user_id uuid not null references users(id) on delete cascade,
+  body text not null,
+  created_at timestamp not null,
+  updated_at timestamp not null,
+  primary key(id)
+);
+
+create index drafts_user_id_idx on drafts(user_id, updated_at);
+
+-- +goose Down
+drop table drafts;
@@ -0,0 +1,17 @@
+-- +goose Up
+alter table chirps add column deleted_at timestamp;
+
+create index chirps_deleted_at_idx on chirps(deleted_at) where deleted_at is not null;
+
+-- A deleted rechirp mustn't stop the user from rechirping the same chirp
+-- again while it waits to be purged.
+drop index chirps_user_id_rechirp_of_id_idx;
+create unique index chirps_user_id_rechirp_of_id_idx on chirps(user_id, rechirp_of_id)
+where is_rechirp and body = '' and deleted_at is null;
+
+-- +goose Down
+drop index chirps_user_id_rechirp_of_id_idx;
+create unique index chirps_user_id_rechirp_of_id_idx on chirps(user_id, rechirp_of_id)
+where is_rechirp and body = '';
+
+alter table chirps drop column deleted_at;
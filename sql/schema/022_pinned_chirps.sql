@@ -0,0 +1,7 @@
+-- +goose Up
+alter table chirps add column pinned_at timestamp;
+
+create index chirps_pinned_idx on chirps(user_id, pinned_at) where pinned_at is not null;
+
+-- +goose Down
+alter table chirps drop column pinned_at;
@@ -0,0 +1,11 @@
+-- +goose Up
+alter table users
+  add column display_name text not null default '',
+  add column bio text not null default '',
+  add column avatar_url text not null default '';
+
+-- +goose Down
+alter table users
+  drop column display_name,
+  drop column bio,
+  drop column avatar_url;
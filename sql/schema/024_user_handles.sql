@@ -0,0 +1,7 @@
+-- +goose Up
+alter table users add column handle text;
+
+create unique index users_handle_idx on users(lower(handle));
+
+-- +goose Down
+alter table users drop column handle;
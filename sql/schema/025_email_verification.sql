@@ -0,0 +1,18 @@
+-- +goose Up
+alter table users add column email_verified_at timestamp;
+
+create table email_verification_tokens (
+  token_hash text primary key,
+  user_id uuid not null references users on delete cascade,
+  email text not null,
+  created_at timestamp not null,
+  expires_at timestamp not null,
+  used_at timestamp
+);
+
+create index email_verification_tokens_user_id_idx on email_verification_tokens(user_id);
+
+-- +goose Down
+drop table email_verification_tokens;
+
+alter table users drop column email_verified_at;
@@ -0,0 +1,13 @@
+-- +goose Up
+create table password_reset_tokens (
+  token_hash text primary key,
+  user_id uuid not null references users on delete cascade,
+  created_at timestamp not null,
+  expires_at timestamp not null,
+  used_at timestamp
+);
+
+create index password_reset_tokens_user_id_idx on password_reset_tokens(user_id);
+
+-- +goose Down
+drop table password_reset_tokens;
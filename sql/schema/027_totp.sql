@@ -0,0 +1,23 @@
+-- +goose Up
+create table user_totp (
+  user_id uuid primary key references users on delete cascade,
+  -- The secret has to be readable to compute codes, so unlike passwords
+  -- and tokens it can't be stored hashed.
+  secret text not null,
+  created_at timestamp not null,
+  confirmed_at timestamp,
+  last_used_step bigint not null default 0
+);
+
+create table recovery_codes (
+  code_hash text primary key,
+  user_id uuid not null references users on delete cascade,
+  created_at timestamp not null,
+  used_at timestamp
+);
+
+create index recovery_codes_user_id_idx on recovery_codes(user_id);
+
+-- +goose Down
+drop table recovery_codes;
+drop table user_totp;
@@ -0,0 +1,9 @@
+-- +goose Up
+alter table user_totp
+  add column failed_attempts integer not null default 0,
+  add column locked_until timestamp;
+
+-- +goose Down
+alter table user_totp
+  drop column failed_attempts,
+  drop column locked_until;
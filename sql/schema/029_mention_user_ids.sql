@@ -0,0 +1,41 @@
+-- +goose Up
+alter table chirp_mentions
+  add column user_id uuid references users(id) on delete cascade;
+
+update chirp_mentions
+set user_id = users.id
+from users
+where lower(users.handle) = chirp_mentions.handle;
+
+delete from chirp_mentions
+where user_id is null;
+
+alter table chirp_mentions
+  drop constraint chirp_mentions_pkey,
+  drop column handle,
+  alter column user_id set not null,
+  add primary key(chirp_id, user_id);
+
+create index chirp_mentions_user_id_idx on chirp_mentions(user_id, created_at);
+
+-- +goose Down
+drop index chirp_mentions_user_id_idx;
+
+alter table chirp_mentions
+  add column handle text;
+
+update chirp_mentions
+set handle = lower(users.handle)
+from users
+where users.id = chirp_mentions.user_id;
+
+delete from chirp_mentions
+where handle is null;
+
+alter table chirp_mentions
+  drop constraint chirp_mentions_pkey,
+  drop column user_id,
+  alter column handle set not null,
+  add primary key(chirp_id, handle);
+
+create index chirp_mentions_handle_idx on chirp_mentions(handle, created_at);
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/17xande/bd-chirpy/internal/database"
+)
+
+const maxTagLength = 100
+
+// extractHashtags returns the distinct #hashtags in body, lowercased and in
+// order of first appearance.
+func extractHashtags(body string) []string {
+	return extractPrefixed(body, '#')
+}
+
+// extractMentions returns the distinct @handles in body, lowercased and in
+// order of first appearance.
+func extractMentions(body string) []string {
+	return extractPrefixed(body, '@')
+}
+
+// extractPrefixed finds words that start with prefix. The prefix only counts
+// at the start of the body or after a character that can't be part of a word,
+// so "me@example.com" doesn't mention anyone.
+func extractPrefixed(body string, prefix rune) []string {
+	found := []string{}
+	seen := map[string]bool{}
+	prev := ' '
+
+	for i, r := range body {
+		if r != prefix || isTagRune(prev) {
+			prev = r
+			continue
+		}
+		prev = r
+
+		start := i + utf8.RuneLen(r)
+		end := start
+		for end < len(body) {
+			next, size := utf8.DecodeRuneInString(body[end:])
+			if !isTagRune(next) {
+				break
+			}
+			end += size
+		}
+
+		tag := strings.ToLower(body[start:end])
+		if tag == "" || utf8.RuneCountInString(tag) > maxTagLength || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		found = append(found, tag)
+	}
+
+	return found
+}
+
+func isTagRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// indexChirpTags replaces the hashtags and mentions stored for chirp with the
+// ones in its current body. Callers run it in the transaction that writes the
+// chirp so the indexes never disagree with the body. Mentions are stored as
+// the user who holds the handle right now, so a handle changing hands later
+// doesn't take past mentions with it; handles nobody holds are skipped.
+func indexChirpTags(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	if err := q.DeleteChirpHashtags(ctx, chirp.ID); err != nil {
+		return fmt.Errorf("can't clear hashtags: %w", err)
+	}
+
+	for _, tag := range extractHashtags(chirp.Body) {
+		hashtag, err := q.UpsertHashtag(ctx, tag)
+		if err != nil {
+			return fmt.Errorf("can't store hashtag %q: %w", tag, err)
+		}
+
+		err = q.AddChirpHashtag(ctx, database.AddChirpHashtagParams{
+			ChirpID:   chirp.ID,
+			HashtagID: hashtag.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("can't tag chirp with %q: %w", tag, err)
+		}
+	}
+
+	if err := q.DeleteChirpMentions(ctx, chirp.ID); err != nil {
+		return fmt.Errorf("can't clear mentions: %w", err)
+	}
+
+	for _, handle := range extractMentions(chirp.Body) {
+		err := q.AddChirpMention(ctx, database.AddChirpMentionParams{
+			ChirpID: chirp.ID,
+			Handle:  handle,
+		})
+		if err != nil {
+			return fmt.Errorf("can't store mention of %q: %w", handle, err)
+		}
+	}
+
+	return nil
+}